@@ -0,0 +1,129 @@
+package log
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// maxCallers is how many frames Caller looks at.
+const maxCallers = 64
+
+// pcPool holds the buffers Caller reads the stack into.
+var pcPool = sync.Pool{
+	New: func() interface{} { return new([maxCallers]uintptr) },
+}
+
+// logPackage is the import path of this package, vendored or not.
+var logPackage = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(NewEntry).Pointer()).Name()
+	return strings.TrimSuffix(name, ".NewEntry")
+}()
+
+var (
+	// helpers are the functions marked with Helper.
+	helpers sync.Map
+
+	skipMu sync.RWMutex
+	// skipPrefixes are the function name prefixes of skipped packages,
+	// this package, its handlers, the runtime, the standard library
+	// loggers bridged to it and fmt, which writes to a Writer for
+	// Fprintf, are always skipped.
+	skipPrefixes = []string{logPackage + ".", logPackage + "/handlers/", "runtime.", "log.", "log/slog.", "fmt."}
+)
+
+// Helper marks the calling function as a logging helper. Entries logged
+// through it report the caller of the helper as their file and function,
+// like testing.T.Helper.
+//
+//	func logRequest(r *http.Request) {
+//		log.Helper()
+//		log.WithField("path", r.URL.Path).Info("request")
+//	}
+func Helper() {
+	pc, _, _, ok := runtime.Caller(1)
+	if !ok {
+		return
+	}
+
+	if f := runtime.FuncForPC(pc); f != nil {
+		if _, ok := helpers.Load(f.Name()); !ok {
+			helpers.Store(f.Name(), true)
+		}
+	}
+}
+
+// SkipPackages marks whole packages, such as a wrapper around this one,
+// as logging helpers. Entries report the first caller outside of them.
+func SkipPackages(paths ...string) {
+	skipMu.Lock()
+	for _, path := range paths {
+		skipPrefixes = append(skipPrefixes, path+".")
+	}
+	skipMu.Unlock()
+}
+
+// skipped reports whether the frame of `function` is part of logging
+// rather than the caller.
+func skipped(function string) bool {
+	if _, ok := helpers.Load(function); ok {
+		return true
+	}
+
+	skipMu.RLock()
+	defer skipMu.RUnlock()
+
+	for _, prefix := range skipPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFunc is the function every entry passes through, frames above it
+// are the call site.
+var writeFunc = logPackage + ".(*Logger).write"
+
+// callers calls `fn` with the frames above Logger.write, leaving out the
+// frames of this package, its handlers and the helpers leading up to it
+// and the runtime, until `fn` returns false. It walks the current stack,
+// so it only works while an entry is written.
+func callers(fn func(runtime.Frame) bool) {
+	pcs := pcPool.Get().(*[maxCallers]uintptr)
+	defer pcPool.Put(pcs)
+	n := runtime.Callers(3, pcs[:])
+
+	frames := runtime.CallersFrames(pcs[:n])
+	written, leading := false, true
+	for {
+		frame, more := frames.Next()
+		switch {
+		case !written:
+			written = frame.Function == writeFunc
+		case leading && skipped(frame.Function):
+		case strings.HasPrefix(frame.Function, "runtime."):
+		default:
+			leading = false
+			if !fn(frame) {
+				return
+			}
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+// Caller returns the frame that logged the entry, the first one above
+// Logger.Write outside of this package, its handlers and the helpers.
+// It walks the current stack, so it only works from the hooks and
+// reporters while the entry is written.
+func (e *Entry) Caller() (frame runtime.Frame, ok bool) {
+	callers(func(f runtime.Frame) bool {
+		frame, ok = f, true
+		return false
+	})
+	return frame, ok
+}
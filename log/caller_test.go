@@ -0,0 +1,181 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+// The tests are in package log_test, frames in package log are skipped
+// when resolving the caller.
+
+// callerRecorder records the caller of every entry, as Entry.Caller and
+// as the ShortFile of a formatter report it.
+type callerRecorder struct {
+	frames []runtime.Frame
+	files  []string
+}
+
+var shortFile = log.MustStringFormatter("{{ .ShortFile }}")
+
+func (r *callerRecorder) Write(e *log.Entry, calldepth int) error {
+	frame, _ := e.Caller()
+	r.frames = append(r.frames, frame)
+
+	file, err := shortFile.Finalize(shortFile.Format(e, calldepth+1))
+	r.files = append(r.files, file)
+	return err
+}
+
+// here returns the line it is called from.
+func here() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// logHelper is marked with log.Helper, entries report its caller.
+func logHelper(l *log.Logger) {
+	log.Helper()
+	l.Info("from helper")
+}
+
+func TestCaller(t *testing.T) {
+	rec := &callerRecorder{}
+	l := log.NewLogger(log.DEBUG, []log.Reporter{rec})
+	filtered := log.NewLogger(log.DEBUG, []log.Reporter{log.Filtered(rec, log.MinLevel(log.DEBUG))})
+
+	defer log.SetDefault(log.SetDefault(l))
+
+	tests := []struct {
+		name string
+		// log logs one entry and returns the line that logged it
+		log func() int
+	}{
+		{"Info", func() int {
+			line := here() + 1
+			l.Info("x")
+			return line
+		}},
+		{"Infof", func() int {
+			line := here() + 1
+			l.Infof("x %d", 1)
+			return line
+		}},
+		{"Debugf", func() int {
+			line := here() + 1
+			l.Debugf("x %d", 1)
+			return line
+		}},
+		{"WithField", func() int {
+			line := here() + 1
+			l.WithField("k", "v").Info("x")
+			return line
+		}},
+		{"WithFields", func() int {
+			line := here() + 1
+			l.WithFields(log.Fields{"k": "v"}).Warnf("x %d", 1)
+			return line
+		}},
+		{"WithError", func() int {
+			line := here() + 1
+			l.WithError(errors.New("failed")).Error("x")
+			return line
+		}},
+		{"reused entry", func() int {
+			e := l.WithField("k", "v")
+			e.Info("first")
+			line := here() + 1
+			e.Info("second")
+			return line
+		}},
+		{"Trace", func() int {
+			line := here() + 1
+			l.Trace("x")
+			return line
+		}},
+		{"Stop", func() int {
+			e := log.NewEntry(log.NewLogger(log.DEBUG, nil)).Trace("x")
+			e.Logger = l
+			var err error
+			line := here() + 1
+			e.Stop(&err)
+			return line
+		}},
+		{"package Info", func() int {
+			line := here() + 1
+			log.Info("x")
+			return line
+		}},
+		{"package Errorf", func() int {
+			line := here() + 1
+			log.Errorf("x %d", 1)
+			return line
+		}},
+		{"package WithField", func() int {
+			line := here() + 1
+			log.WithField("k", "v").Debug("x")
+			return line
+		}},
+		{"package Trace", func() int {
+			line := here() + 1
+			log.Trace("x")
+			return line
+		}},
+		{"Named", func() int {
+			line := here() + 1
+			l.Named("sub").Info("x")
+			return line
+		}},
+		{"package Named", func() int {
+			line := here() + 1
+			log.Named("sub").Named("inner").Warn("x")
+			return line
+		}},
+		{"Helper", func() int {
+			line := here() + 1
+			logHelper(l)
+			return line
+		}},
+		{"std logger", func() int {
+			std := log.NewStdLogger(l, log.INFO)
+			line := here() + 1
+			std.Print("x")
+			return line
+		}},
+		{"std writer", func() int {
+			w := log.NewWriter(l, log.INFO)
+			line := here() + 1
+			fmt.Fprintln(w, "x")
+			return line
+		}},
+		{"Filtered", func() int {
+			line := here() + 1
+			filtered.Info("x")
+			return line
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec.frames, rec.files = nil, nil
+			line := tt.log()
+
+			if len(rec.frames) == 0 {
+				t.Fatal("nothing logged")
+			}
+			frame := rec.frames[len(rec.frames)-1]
+			if filepath.Base(frame.File) != "caller_test.go" || frame.Line != line {
+				t.Errorf("Caller = %s:%d (%s), want caller_test.go:%d", filepath.Base(frame.File), frame.Line, frame.Function, line)
+			}
+
+			want := fmt.Sprintf("caller_test.go:%d", line)
+			if file := rec.files[len(rec.files)-1]; file != want {
+				t.Errorf("ShortFile = %s, want %s", file, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// bufPool holds the buffers formatters render into.
+var bufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// callerFields are the data fields that need runtime.Caller.
+var callerFields = map[string]bool{
+	"LongFile":  true,
+	"ShortFile": true,
+	"LongPkg":   true,
+	"ShortPkg":  true,
+	"LongFunc":  true,
+	"ShortFunc": true,
+}
+
+// funcFields are the caller fields that also need runtime.FuncForPC.
+var funcFields = map[string]bool{
+	"LongPkg":   true,
+	"ShortPkg":  true,
+	"LongFunc":  true,
+	"ShortFunc": true,
+}
+
+// usage is the set of data fields a template reads, all of them when
+// the template hands the whole data to something, such as {{ template }},
+// {{ with }} or a function called with the dot.
+type usage struct {
+	all    bool
+	fields map[string]bool
+}
+
+func (u *usage) uses(name string) bool {
+	return u.all || u.fields[name]
+}
+
+func (u *usage) usesAny(names map[string]bool) bool {
+	if u.all {
+		return true
+	}
+	for name := range u.fields {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// analyse returns the data fields `tree` reads.
+func analyse(tree *parse.Tree) *usage {
+	u := &usage{fields: map[string]bool{}}
+	if tree == nil || tree.Root == nil {
+		u.all = true
+		return u
+	}
+	u.walk(tree.Root)
+	return u
+}
+
+func (u *usage) walk(node parse.Node) {
+	if u.all || node == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			u.walk(c)
+		}
+	case *parse.ActionNode:
+		u.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			u.walk(c)
+		}
+	case *parse.CommandNode:
+		for _, c := range n.Args {
+			u.walk(c)
+		}
+	case *parse.IfNode:
+		u.walk(n.Pipe)
+		u.walk(n.List)
+		u.walk(n.ElseList)
+	case *parse.FieldNode:
+		u.fields[n.Ident[0]] = true
+	case *parse.ChainNode:
+		u.walk(n.Node)
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" || len(n.Ident) < 2 {
+			// local variables may hold anything, be safe
+			u.all = true
+			return
+		}
+		u.fields[n.Ident[1]] = true
+	case *parse.DotNode, *parse.RangeNode, *parse.WithNode, *parse.TemplateNode:
+		u.all = true
+	case *parse.TextNode, *parse.StringNode, *parse.NumberNode, *parse.BoolNode,
+		*parse.NilNode, *parse.IdentifierNode, *parse.CommentNode:
+	default:
+		u.all = true
+	}
+}
+
+// segment is a part of a compiled format.
+type segment struct {
+	text   string // written as is when field is empty
+	field  string // data field to write
+	layout string // time layout when the field is formatted with formatTime
+}
+
+// compile turns templates made only of text, {{ .Field }}, constant
+// strings and {{ formatTime .Field "layout" }} into segments that are
+// written without running text/template. It returns false for any other
+// template. `custom` are the functions added with WithFuncs.
+func compile(tree *parse.Tree, custom template.FuncMap) ([]segment, bool) {
+	if tree == nil || tree.Root == nil {
+		return nil, false
+	}
+
+	var segments []segment
+	for _, node := range tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			segments = append(segments, segment{text: string(n.Text)})
+
+		case *parse.ActionNode:
+			if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) != 1 {
+				return nil, false
+			}
+			s, ok := compileCommand(n.Pipe.Cmds[0], custom)
+			if !ok {
+				return nil, false
+			}
+			segments = append(segments, s)
+
+		case *parse.CommentNode:
+
+		default:
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+func compileCommand(cmd *parse.CommandNode, custom template.FuncMap) (segment, bool) {
+	switch len(cmd.Args) {
+	case 1:
+		switch arg := cmd.Args[0].(type) {
+		case *parse.FieldNode:
+			if len(arg.Ident) == 1 {
+				return segment{field: arg.Ident[0]}, true
+			}
+		case *parse.StringNode:
+			return segment{text: arg.Text}, true
+		}
+
+	case 3:
+		// formatTime, unless WithFuncs replaced it
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok || ident.Ident != "formatTime" || custom[ident.Ident] != nil {
+			return segment{}, false
+		}
+		field, ok := cmd.Args[1].(*parse.FieldNode)
+		if !ok || len(field.Ident) != 1 {
+			return segment{}, false
+		}
+		layout, ok := cmd.Args[2].(*parse.StringNode)
+		if !ok {
+			return segment{}, false
+		}
+		return segment{field: field.Ident[0], layout: layout.Text}, true
+	}
+	return segment{}, false
+}
+
+// execute writes the segments with the values of `data`, as text/template
+// would.
+func execute(b *bytes.Buffer, segments []segment, data map[string]interface{}) error {
+	var scratch [64]byte
+	for _, s := range segments {
+		if s.field == "" {
+			b.WriteString(s.text)
+			continue
+		}
+
+		v, ok := data[s.field]
+		if !ok {
+			b.WriteString("<no value>")
+			continue
+		}
+
+		if s.layout != "" {
+			t, ok := v.(time.Time)
+			if !ok {
+				return fmt.Errorf("formatTime: %s is %T, not time.Time", s.field, v)
+			}
+			b.Write(t.AppendFormat(scratch[:0], s.layout))
+			continue
+		}
+
+		switch v := v.(type) {
+		case string:
+			b.WriteString(v)
+		case int:
+			b.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+		default:
+			fmt.Fprint(b, v)
+		}
+	}
+	return nil
+}
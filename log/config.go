@@ -0,0 +1,341 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is a declarative logger configuration, as read by LoadConfig
+// from JSON or YAML or by EnvConfig from the environment.
+//
+//	level: info
+//	levels:
+//	  nats: debug
+//	reporters:
+//	  - type: cli
+//	  - type: file
+//	    level: warn
+//	    options:
+//	      folder: /var/log/keiwi
+//	      max_size: 10MB
+type Config struct {
+	// Level is the root level, INFO when empty.
+	Level string `json:"level" yaml:"level"`
+	// Levels are the levels of named loggers, see SetLevels.
+	Levels map[string]string `json:"levels" yaml:"levels"`
+	// StackLevel is the level up to which entries get a call stack, see
+	// SetStackLevel. No stacks are captured when empty.
+	StackLevel string `json:"stack_level" yaml:"stack_level"`
+	// Reporters are created in order with the registered factories.
+	Reporters []ReporterConfig `json:"reporters" yaml:"reporters"`
+}
+
+// ReporterConfig configures a single reporter.
+type ReporterConfig struct {
+	// Type is the name the reporter factory was registered with.
+	Type string `json:"type" yaml:"type"`
+	// Level is the most verbose level written to the reporter, all
+	// entries the logger writes when empty.
+	Level string `json:"level" yaml:"level"`
+	// Format is the template of an entry, passed to the factory as the
+	// "format" option.
+	Format string `json:"format" yaml:"format"`
+	// Options are passed to the factory.
+	Options Options `json:"options" yaml:"options"`
+}
+
+// ReporterFactory creates a reporter from declarative options.
+type ReporterFactory func(o Options) (Reporter, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]ReporterFactory{}
+)
+
+// RegisterReporter makes a reporter type available to FromConfig. The
+// handler packages register themselves when imported, so import the
+// handlers a config may use:
+//
+//	import _ "github.com/keiwi/utils/log/handlers/file"
+func RegisterReporter(name string, factory ReporterFactory) {
+	factoriesMu.Lock()
+	factories[name] = factory
+	factoriesMu.Unlock()
+}
+
+// reporterFactory returns the factory registered as `name`.
+func reporterFactory(name string) (ReporterFactory, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[name]
+	if !ok {
+		names := make([]string, 0, len(factories))
+		for name := range factories {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown reporter type %q, registered are %s", name, strings.Join(names, ", "))
+	}
+	return factory, nil
+}
+
+// LoadConfig reads a config file, YAML when the extension is .yaml or
+// .yml and JSON otherwise.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading log config %s: %v", path, err)
+	}
+	return &config, nil
+}
+
+// EnvConfig reads a config from the environment variables starting with
+// `prefix`. With prefix "LOG_":
+//
+//	LOG_LEVEL=info
+//	LOG_LEVELS=nats=debug,scheduler=warn
+//	LOG_STACK_LEVEL=error
+//	LOG_REPORTERS=cli,file
+//	LOG_FILE_LEVEL=warn
+//	LOG_FILE_MAX_SIZE=10MB
+//
+// The variables of a reporter start with its upper case type, LEVEL and
+// FORMAT set the reporter level and format and the rest are options.
+func EnvConfig(prefix string) *Config {
+	config := &Config{
+		Level:      os.Getenv(prefix + "LEVEL"),
+		Levels:     map[string]string{},
+		StackLevel: os.Getenv(prefix + "STACK_LEVEL"),
+	}
+
+	for k, v := range splitMap(os.Getenv(prefix + "LEVELS")) {
+		config.Levels[k] = fmt.Sprint(v)
+	}
+
+	for _, t := range splitList(os.Getenv(prefix + "REPORTERS")) {
+		name := t.(string)
+		o := EnvOptions(prefix + strings.ToUpper(name) + "_")
+
+		rc := ReporterConfig{Type: name, Options: o}
+		if v, ok := o["level"]; ok {
+			rc.Level = fmt.Sprint(v)
+			delete(o, "level")
+		}
+		if v, ok := o["format"]; ok {
+			rc.Format = fmt.Sprint(v)
+			delete(o, "format")
+		}
+		config.Reporters = append(config.Reporters, rc)
+	}
+
+	return config
+}
+
+// spec returns the levels in the format read by SetLevels.
+func (c *Config) spec() (string, error) {
+	parts := []string{"info"}
+	if c.Level != "" {
+		if _, err := ParseLevel(c.Level); err != nil {
+			return "", err
+		}
+		parts[0] = c.Level
+	}
+
+	for name, level := range c.Levels {
+		if _, err := ParseLevel(level); err != nil {
+			return "", fmt.Errorf("level of %q: %v", name, err)
+		}
+		parts = append(parts, name+"="+level)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// reporters creates the reporters of the config, closing the ones
+// already created when one fails.
+func (c *Config) reporters() ([]Reporter, error) {
+	var reporters []Reporter
+	for i, rc := range c.Reporters {
+		r, err := rc.reporter()
+		if err != nil {
+			closeReporters(reporters)
+			return nil, fmt.Errorf("reporter %d (%s): %v", i, rc.Type, err)
+		}
+		reporters = append(reporters, r)
+	}
+	return reporters, nil
+}
+
+// reporter creates the reporter with the registered factory.
+func (rc *ReporterConfig) reporter() (Reporter, error) {
+	factory, err := reporterFactory(rc.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	o := Options{}
+	for k, v := range rc.Options {
+		o[k] = v
+	}
+	if rc.Format != "" {
+		o["format"] = rc.Format
+	}
+
+	level := DEBUG
+	if rc.Level != "" {
+		if level, err = ParseLevel(rc.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := factory(o)
+	if err != nil {
+		return nil, err
+	}
+	return &configured{Reporter: r, name: rc.Type, level: level}, nil
+}
+
+// configured is a reporter created from a config, it is closed when the
+// config is replaced.
+type configured struct {
+	Reporter
+	name  string
+	level Level
+}
+
+func (c *configured) Write(e *Entry, calldepth int) error {
+	if e.Level > c.level {
+		return nil
+	}
+	return c.Reporter.Write(e, calldepth+1)
+}
+
+func (c *configured) wants(e *Entry) bool {
+	return e.Level <= c.level && wants(c.Reporter, e)
+}
+
+// Close closes the reporter when it is an io.Closer.
+func (c *configured) Close() error {
+	return closeReporter(c.Reporter)
+}
+
+// Flush flushes the reporter when it buffers entries.
+func (c *configured) Flush() error {
+	return flushReporter(c.Reporter)
+}
+
+// closeReporters closes the reporters created from a config.
+func closeReporters(reporters []Reporter) {
+	for _, r := range reporters {
+		if c, ok := r.(*configured); ok {
+			c.Close()
+		}
+	}
+}
+
+// FromConfig creates a logger from `config`.
+func FromConfig(config *Config) (*Logger, error) {
+	l := NewLogger(INFO, nil)
+	if err := l.ApplyConfig(config); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ApplyConfig replaces the levels and reporters of the logger with the
+// ones of `config`. Reporters created by an earlier config are closed,
+// on error the logger is left unchanged.
+func (l *Logger) ApplyConfig(config *Config) error {
+	spec, err := config.spec()
+	if err != nil {
+		return err
+	}
+
+	stackLevel := NoStack
+	if config.StackLevel != "" {
+		if stackLevel, err = ParseLevel(config.StackLevel); err != nil {
+			return fmt.Errorf("stack level: %v", err)
+		}
+	}
+
+	reporters, err := config.reporters()
+	if err != nil {
+		return err
+	}
+
+	root := l.core()
+	if err := root.SetLevels(spec); err != nil {
+		closeReporters(reporters)
+		return err
+	}
+
+	root.SetStackLevel(stackLevel)
+
+	root.Lock()
+	old := root.Reporters
+	root.Reporters = reporters
+	root.Unlock()
+
+	closeReporters(old)
+	return nil
+}
+
+// WatchConfig applies the config file at `path` to `l` whenever it
+// changes, checking every `interval`. A config that fails to load or
+// apply is logged and the previous one is kept. Call the returned
+// function to stop watching.
+func WatchConfig(path string, l *Logger, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	last, _ := os.Stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fi, err := os.Stat(path)
+				if err != nil || last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+					continue
+				}
+				last = fi
+
+				config, err := LoadConfig(path)
+				if err == nil {
+					err = l.ApplyConfig(config)
+				}
+				if err != nil {
+					l.WithError(err).WithField("path", path).Error("reloading log config")
+					continue
+				}
+				l.WithField("path", path).Info("reloaded log config")
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
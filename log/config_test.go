@@ -0,0 +1,264 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+// options are the options the "test" reporter factory was last called with.
+var (
+	optionsMu sync.Mutex
+	options   log.Options
+)
+
+func init() {
+	log.RegisterReporter("test", func(o log.Options) (log.Reporter, error) {
+		optionsMu.Lock()
+		options = o
+		optionsMu.Unlock()
+		return logtest.New(), nil
+	})
+}
+
+// writeConfig writes `data` to `name` in a temporary directory.
+func writeConfig(t *testing.T, dir, name, data string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"log.json": `{"level": "warn", "levels": {"nats": "debug"}, "stack_level": "error",
+			"reporters": [{"type": "test", "level": "error", "format": "{{ .Message }}", "options": {"max_size": "10MB"}}]}`,
+		"log.yaml": "level: warn\nlevels:\n  nats: debug\nstack_level: error\nreporters:\n  - type: test\n    level: error\n    format: \"{{ .Message }}\"\n    options:\n      max_size: 10MB\n",
+	}
+
+	for name, data := range files {
+		config, err := log.LoadConfig(writeConfig(t, dir, name, data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if config.Level != "warn" || config.Levels["nats"] != "debug" || config.StackLevel != "error" {
+			t.Errorf("%s: levels %+v", name, config)
+		}
+		if len(config.Reporters) != 1 {
+			t.Fatalf("%s: reporters %+v", name, config.Reporters)
+		}
+		rc := config.Reporters[0]
+		if rc.Type != "test" || rc.Level != "error" || rc.Format != "{{ .Message }}" || rc.Options["max_size"] != "10MB" {
+			t.Errorf("%s: reporter %+v", name, rc)
+		}
+
+		l, err := log.FromConfig(config)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if l.GetLevel() != log.WARN || l.Named("nats").GetLevel() != log.DEBUG || l.GetStackLevel() != log.ERROR {
+			t.Errorf("%s: logger levels %s", name, l.LevelSpec())
+		}
+
+		optionsMu.Lock()
+		if options["format"] != "{{ .Message }}" || options["max_size"] != "10MB" {
+			t.Errorf("%s: factory options %v", name, options)
+		}
+		optionsMu.Unlock()
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := log.LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file loaded")
+	}
+	if _, err := log.LoadConfig(writeConfig(t, dir, "bad.json", "{")); err == nil {
+		t.Error("invalid JSON loaded")
+	}
+
+	bad := []*log.Config{
+		{Level: "loud"},
+		{Levels: map[string]string{"nats": "loud"}},
+		{StackLevel: "loud"},
+		{Reporters: []log.ReporterConfig{{Type: "unregistered"}}},
+		{Reporters: []log.ReporterConfig{{Type: "test", Level: "loud"}}},
+	}
+	for _, config := range bad {
+		if _, err := log.FromConfig(config); err == nil {
+			t.Errorf("config %+v applied", config)
+		}
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	env := map[string]string{
+		"TESTCONFIG_LEVEL":          "warn",
+		"TESTCONFIG_LEVELS":         "nats=debug, scheduler=error",
+		"TESTCONFIG_STACK_LEVEL":    "error",
+		"TESTCONFIG_REPORTERS":      "test",
+		"TESTCONFIG_TEST_LEVEL":     "info",
+		"TESTCONFIG_TEST_FORMAT":    "{{ .Message }}",
+		"TESTCONFIG_TEST_MAX_SIZE":  "10MB",
+		"TESTCONFIG_OTHER_MAX_SIZE": "1MB",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config := log.EnvConfig("TESTCONFIG_")
+	if config.Level != "warn" || config.StackLevel != "error" {
+		t.Errorf("levels %+v", config)
+	}
+	if config.Levels["nats"] != "debug" || config.Levels["scheduler"] != "error" {
+		t.Errorf("named levels %v", config.Levels)
+	}
+	if len(config.Reporters) != 1 {
+		t.Fatalf("reporters %+v", config.Reporters)
+	}
+
+	rc := config.Reporters[0]
+	if rc.Type != "test" || rc.Level != "info" || rc.Format != "{{ .Message }}" {
+		t.Errorf("reporter %+v", rc)
+	}
+	if len(rc.Options) != 1 || rc.Options["max_size"] != "10MB" {
+		t.Errorf("options %v, want only max_size", rc.Options)
+	}
+}
+
+type decoded struct {
+	MaxSize    int64
+	MaxLines   int
+	MaxAge     time.Duration
+	Mode       os.FileMode
+	Compress   bool
+	Level      log.Level
+	Tags       []string
+	LevelFiles map[log.Level]string
+	Named      string `json:"name"`
+	Nested     struct {
+		BufferSize uint
+	}
+}
+
+func TestOptionsDecode(t *testing.T) {
+	var d decoded
+	err := log.Options{
+		"max_size":    "10MB",
+		"max_lines":   "1000",
+		"max_age":     "24h",
+		"mode":        "0640",
+		"compress":    "true",
+		"level":       "warn",
+		"tags":        "a, b",
+		"level_files": "error=errors.log,warn=warn.log",
+		"name":        "app",
+		"nested":      map[interface{}]interface{}{"buffer_size": "4KB"},
+	}.Decode(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.MaxSize != 10<<20 || d.MaxLines != 1000 || d.MaxAge != 24*time.Hour || d.Mode != 0640 {
+		t.Errorf("decoded %+v", d)
+	}
+	if !d.Compress || d.Level != log.WARN || d.Named != "app" || d.Nested.BufferSize != 4<<10 {
+		t.Errorf("decoded %+v", d)
+	}
+	if len(d.Tags) != 2 || d.Tags[0] != "a" || d.Tags[1] != "b" {
+		t.Errorf("tags = %q", d.Tags)
+	}
+	if d.LevelFiles[log.ERROR] != "errors.log" || d.LevelFiles[log.WARN] != "warn.log" {
+		t.Errorf("level files = %v", d.LevelFiles)
+	}
+}
+
+func TestOptionsDecodeNumbers(t *testing.T) {
+	var d decoded
+	err := log.Options{"max_size": float64(1024), "max_lines": 10}.Decode(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.MaxSize != 1024 || d.MaxLines != 10 {
+		t.Errorf("decoded %+v", d)
+	}
+}
+
+func TestOptionsDecodeErrors(t *testing.T) {
+	bad := []log.Options{
+		{"unknown": "1"},
+		{"Named": "app"},
+		{"max_lines": "1KB"},
+		{"max_size": "big"},
+		{"max_age": "1 day"},
+		{"mode": "rw"},
+		{"level": "loud"},
+		{"compress": []string{"yes"}},
+	}
+	for _, o := range bad {
+		var d decoded
+		if err := o.Decode(&d); err == nil {
+			t.Errorf("options %v decoded to %+v", o, d)
+		}
+	}
+
+	if err := (log.Options{}).Decode(decoded{}); err == nil {
+		t.Error("decoded into a non pointer")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "log.json", `{"level": "info"}`)
+	l, err := log.FromConfig(&log.Config{Level: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := log.WatchConfig(path, l, 10*time.Millisecond)
+	defer stop()
+
+	writeConfig(t, dir, "log.json", `{"level": "debug", "levels": {"nats": "error"}}`)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.GetLevel() != log.DEBUG || l.Named("nats").GetLevel() != log.ERROR {
+		if time.Now().After(deadline) {
+			t.Fatalf("config not reloaded, levels %s", l.LevelSpec())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// an invalid config keeps the previous one
+	writeConfig(t, dir, "log.json", `{"level": "loud"}`)
+	time.Sleep(50 * time.Millisecond)
+	if l.GetLevel() != log.DEBUG {
+		t.Errorf("levels %s after an invalid config, want DEBUG", l.LevelSpec())
+	}
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+// entryKey is the context key holding an *Entry.
+type entryKey struct{}
+
+// contextKeys holds the context keys copied into fields by WithContext.
+var contextKeys = struct {
+	sync.RWMutex
+	names map[interface{}]string
+}{names: map[interface{}]string{}}
+
+// NewContext returns a copy of `ctx` carrying `e`.
+func NewContext(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, e)
+}
+
+// FromContext returns the entry carried by `ctx`, or a new entry of the
+// default logger when there is none.
+func FromContext(ctx context.Context) *Entry {
+	if e, ok := ctx.Value(entryKey{}).(*Entry); ok && e != nil {
+		return e
+	}
+	return NewEntry(Default())
+}
+
+// RegisterContextKey makes WithContext set the field `name` to the value
+// stored in a context under `key`.
+func RegisterContextKey(key interface{}, name string) {
+	contextKeys.Lock()
+	contextKeys.names[key] = name
+	contextKeys.Unlock()
+}
+
+// contextFields returns the values of the registered keys found in `ctx`.
+func contextFields(ctx context.Context) Fields {
+	f := Fields{}
+
+	contextKeys.RLock()
+	for key, name := range contextKeys.names {
+		if v := ctx.Value(key); v != nil {
+			f[name] = v
+		}
+	}
+	contextKeys.RUnlock()
+
+	return f
+}
+
+// WithContext returns a new entry with the values of the registered
+// context keys found in `ctx` set as fields.
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	return e.WithFields(contextFields(ctx))
+}
+
+// WithContext returns a new entry with the values of the registered
+// context keys found in `ctx` set as fields.
+func (l *Logger) WithContext(ctx context.Context) *Entry {
+	return NewEntry(l).WithContext(ctx)
+}
+
+// WithContext returns the entry carried by `ctx` with the values of the
+// registered context keys set as fields.
+func WithContext(ctx context.Context) *Entry {
+	return FromContext(ctx).WithContext(ctx)
+}
@@ -0,0 +1,57 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+// ctxKey is the type of the context keys registered by the tests.
+type ctxKey string
+
+func TestContextCarriesEntry(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	ctx := log.NewContext(context.Background(), l.WithField("request", "r1"))
+	log.FromContext(ctx).WithField("step", 2).Info("handled")
+
+	if rec.Len() != 1 || !rec.FieldEquals("request", "r1") || !rec.FieldEquals("step", 2) {
+		t.Errorf("recorded %v, want the request and step fields", rec.Entries())
+	}
+}
+
+func TestContextWithoutEntry(t *testing.T) {
+	rec := logtest.Capture(t)
+
+	log.FromContext(context.Background()).Info("default")
+	if !rec.HasMessage("default") {
+		t.Errorf("recorded %v, want the entry on the default logger", rec.Entries())
+	}
+}
+
+func TestContextRegisteredKeys(t *testing.T) {
+	log.RegisterContextKey(ctxKey("trace"), "trace_id")
+	log.RegisterContextKey(ctxKey("user"), "user_id")
+
+	l, rec := logtest.NewLogger()
+	ctx := context.WithValue(context.Background(), ctxKey("trace"), "t1")
+
+	l.WithContext(ctx).Info("logger")
+	if !rec.FieldEquals("trace_id", "t1") {
+		t.Errorf("recorded %v, want trace_id", rec.Entries())
+	}
+	if _, ok := rec.Last().Fields["user_id"]; ok {
+		t.Error("user_id set for a key missing from the context")
+	}
+
+	ctx = log.NewContext(ctx, l.WithField("request", "r1"))
+	ctx = context.WithValue(ctx, ctxKey("user"), 42)
+	log.WithContext(ctx).Info("carried")
+
+	last := rec.Last()
+	if last.Message != "carried" || last.Fields["request"] != "r1" || last.Fields["trace_id"] != "t1" || last.Fields["user_id"] != 42 {
+		t.Errorf("last entry %+v, want the carried entry with both keys", last)
+	}
+}
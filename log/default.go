@@ -3,7 +3,6 @@ package log
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"sort"
 )
 
@@ -34,13 +33,13 @@ func (stdLog) Write(e *Entry, calldepth int) error {
 	sort.Sort(byName(fields))
 
 	var b bytes.Buffer
-	fmt.Fprintf(&b, "%5s %-25s", level, e.Message)
+	fmt.Fprintf(&b, "%5s %-25s", level.Name, e.Message)
 
 	for _, f := range fields {
 		fmt.Fprintf(&b, " %s=%v", f.Name, f.Value)
 	}
 
-	log.Println(b.String())
+	stderr.Println(b.String())
 
 	return nil
 }
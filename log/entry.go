@@ -3,7 +3,6 @@ package log
 import (
 	"fmt"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -17,14 +16,12 @@ type Entry struct {
 	Timestamp time.Time
 	start     time.Time
 	fields    []Fields
-	calldepth int
 }
 
 // NewEntry returns a new entry for `log`.
 func NewEntry(log *Logger) *Entry {
 	return &Entry{
-		Logger:    log,
-		calldepth: 1,
+		Logger: log,
 	}
 }
 
@@ -34,9 +31,8 @@ func (e *Entry) WithFields(fields Fielder) *Entry {
 	f = append(f, e.fields...)
 	f = append(f, fields.Fields())
 	return &Entry{
-		Logger:    e.Logger,
-		fields:    f,
-		calldepth: e.calldepth - 1,
+		Logger: e.Logger,
+		fields: f,
 	}
 }
 
@@ -47,25 +43,24 @@ func (e *Entry) WithField(key string, value interface{}) *Entry {
 
 // WithError returns a new entry with the "error" set to `err`.
 //
-// The given error may implement .Fielder, if it does the method
-// will add all its `.Fields()` into the returned entry.
+// The errors `err` wraps are set as "causes" and the stack of the
+// innermost pkg/errors error as "callstack", with its first frame as
+// "source". The given error may implement .Fielder, if it does the
+// method will add all its `.Fields()` into the returned entry.
 func (e *Entry) WithError(err error) *Entry {
-	//e.calldepth = e.calldepth + 1
 	ctx := e.WithField("error", err.Error())
 
-	if s, ok := err.(stackTracer); ok {
-		frame := s.StackTrace()[0]
-
-		name := fmt.Sprintf("%n", frame)
-		file := fmt.Sprintf("%+s", frame)
-		line := fmt.Sprintf("%d", frame)
-
-		parts := strings.Split(file, "\n\t")
-		if len(parts) > 1 {
-			file = parts[1]
-		}
+	messages, stack := causeChain(err)
+	if len(messages) > 0 {
+		ctx = ctx.WithField("causes", messages)
+	}
 
-		ctx = ctx.WithField("source", fmt.Sprintf("%s: %s:%s", name, file, line))
+	if len(stack) > 0 {
+		frame := stack[0]
+		ctx = ctx.WithFields(Fields{
+			"source":    fmt.Sprintf("%s: %s:%d", formatFuncName("longfunc", frame.Function), frame.File, frame.Line),
+			"callstack": stack,
+		})
 	}
 
 	if f, ok := err.(Fielder); ok {
@@ -77,65 +72,60 @@ func (e *Entry) WithError(err error) *Entry {
 
 // Fatal level message, followed by an exit.
 func (e *Entry) Fatal(msg string) {
-	e.Logger.Write(FATAL, e, msg, e.calldepth+1)
+	e.Logger.Write(FATAL, e, msg, 1)
 	os.Exit(1)
 }
 
 // Error level message.
 func (e *Entry) Error(msg string) {
-	e.Logger.Write(ERROR, e, msg, e.calldepth+1)
+	e.Logger.Write(ERROR, e, msg, 1)
 }
 
 // Warn level message.
 func (e *Entry) Warn(msg string) {
-	e.Logger.Write(WARN, e, msg, e.calldepth+1)
+	e.Logger.Write(WARN, e, msg, 1)
 }
 
 // Info level message.
 func (e *Entry) Info(msg string) {
-	e.Logger.Write(INFO, e, msg, e.calldepth+1)
+	e.Logger.Write(INFO, e, msg, 1)
 }
 
 // Debug level message.
 func (e *Entry) Debug(msg string) {
-	e.Logger.Write(DEBUG, e, msg, e.calldepth+1)
+	e.Logger.Write(DEBUG, e, msg, 1)
 }
 
 // Fatalf level formatted message, followed by an exit.
 func (e *Entry) Fatalf(msg string, v ...interface{}) {
-	e.calldepth = e.calldepth + 1
-	e.Fatal(fmt.Sprintf(msg, v...))
+	e.Logger.Write(FATAL, e, fmt.Sprintf(msg, v...), 1)
+	os.Exit(1)
 }
 
 // Errorf level formatted message.
 func (e *Entry) Errorf(msg string, v ...interface{}) {
-	e.calldepth = e.calldepth + 1
-	e.Error(fmt.Sprintf(msg, v...))
+	e.Logger.Write(ERROR, e, fmt.Sprintf(msg, v...), 1)
 }
 
 // Warnf level formatted message.
 func (e *Entry) Warnf(msg string, v ...interface{}) {
-	e.calldepth = e.calldepth + 1
-	e.Warn(fmt.Sprintf(msg, v...))
+	e.Logger.Write(WARN, e, fmt.Sprintf(msg, v...), 1)
 }
 
 // Infof level formatted message.
 func (e *Entry) Infof(msg string, v ...interface{}) {
-	e.calldepth = e.calldepth + 1
-	e.Info(fmt.Sprintf(msg, v...))
+	e.Logger.Write(INFO, e, fmt.Sprintf(msg, v...), 1)
 }
 
 // Debugf level formatted message.
 func (e *Entry) Debugf(msg string, v ...interface{}) {
-	e.calldepth = e.calldepth + 1
-	e.Debug(fmt.Sprintf(msg, v...))
+	e.Logger.Write(DEBUG, e, fmt.Sprintf(msg, v...), 1)
 }
 
 // Trace returns a new entry with a Stop method to fire off
 // a corresponding completion write, useful with defer.
 func (e *Entry) Trace(msg string) *Entry {
-	e.calldepth = e.calldepth + 1
-	e.Info(msg)
+	e.Logger.Write(INFO, e, msg, 1)
 	v := e.WithFields(e.Fields)
 	v.Message = msg
 	v.start = time.Now()
@@ -145,7 +135,6 @@ func (e *Entry) Trace(msg string) *Entry {
 // Stop should be used with Trace, to fire off the completion message. When
 // an `err` is passed the "error" field is set, and the write level is error.
 func (e *Entry) Stop(err *error) {
-	e.calldepth = e.calldepth + 1
 	if err == nil || *err == nil {
 		e.WithField("duration", time.Since(e.start)).Info(e.Message)
 	} else {
@@ -8,7 +8,13 @@ import (
 )
 
 func main() {
-	l := log.NewLogger(log.DEBUG, []log.Reporter{file.NewFile(".", "%date%.log")})
+	f, err := file.New(file.WithFolder("."), file.WithFilename("%date%.log"))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer f.Close()
+
+	l := log.NewLogger(log.DEBUG, []log.Reporter{f})
 
 	l.Debug("Testing debug")
 	l.Info("Testing info")
@@ -0,0 +1,264 @@
+package log
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Filter reports whether an entry should be passed on to a reporter.
+type Filter func(e *Entry) bool
+
+// MinLevel passes entries that are at least as severe as `level`,
+// MinLevel(WARN) lets WARN, ERROR and FATAL through.
+func MinLevel(level Level) Filter {
+	return func(e *Entry) bool {
+		return e.Level <= level
+	}
+}
+
+// OnlyLevels passes entries with one of the given levels.
+func OnlyLevels(levels ...Level) Filter {
+	return func(e *Entry) bool {
+		for _, l := range levels {
+			if e.Level == l {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasField passes entries that have the field `key` set.
+func HasField(key string) Filter {
+	return func(e *Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	}
+}
+
+// FieldEquals passes entries where the field `key` equals `value`.
+func FieldEquals(key string, value interface{}) Filter {
+	return func(e *Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// MessageContains passes entries where the message contains `s`.
+func MessageContains(s string) Filter {
+	return func(e *Entry) bool {
+		return strings.Contains(e.Message, s)
+	}
+}
+
+// MessageMatches passes entries where the message matches `re`.
+func MessageMatches(re *regexp.Regexp) Filter {
+	return func(e *Entry) bool {
+		return re.MatchString(e.Message)
+	}
+}
+
+// Not inverts `filter`.
+func Not(filter Filter) Filter {
+	return func(e *Entry) bool {
+		return !filter(e)
+	}
+}
+
+// All passes entries accepted by every filter.
+func All(filters ...Filter) Filter {
+	return func(e *Entry) bool {
+		return accepts(filters, e)
+	}
+}
+
+// Any passes entries accepted by at least one filter.
+func Any(filters ...Filter) Filter {
+	return func(e *Entry) bool {
+		for _, f := range filters {
+			if f(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// accepts reports whether every filter accepts `e`.
+func accepts(filters []Filter, e *Entry) bool {
+	for _, f := range filters {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// filtered is a reporter that only writes entries accepted by its filters.
+type filtered struct {
+	reporter Reporter
+	filters  []Filter
+}
+
+// Filtered wraps `r` so that only entries accepted by all `filters`
+// reach it.
+//
+// Filters only see entries that already passed the Logger level, so to
+// send DEBUG to one reporter and WARN to another, set the Logger level to
+// DEBUG and wrap the other reporter with MinLevel(WARN).
+func Filtered(r Reporter, filters ...Filter) Reporter {
+	return &filtered{reporter: r, filters: filters}
+}
+
+func (f *filtered) Write(e *Entry, calldepth int) error {
+	if !accepts(f.filters, e) {
+		return nil
+	}
+	return f.reporter.Write(e, calldepth+1)
+}
+
+func (f *filtered) wants(e *Entry) bool {
+	return accepts(f.filters, e) && wants(f.reporter, e)
+}
+
+// Flush flushes the wrapped reporter when it buffers entries.
+func (f *filtered) Flush() error {
+	return flushReporter(f.reporter)
+}
+
+// Close closes the wrapped reporter when it is an io.Closer.
+func (f *filtered) Close() error {
+	return closeReporter(f.reporter)
+}
+
+// route is a single Mux rule.
+type route struct {
+	reporter Reporter
+	filters  []Filter
+}
+
+// Mux is a reporter that routes entries to other reporters by rule.
+//
+// Rules are tried in the order they were added. By default an entry is
+// written to every matching reporter, set First to stop at the first
+// match. Entries that match no rule go to Fallback, if set.
+type Mux struct {
+	First    bool
+	Fallback Reporter
+	routes   []route
+}
+
+// NewMux creates a new empty mux.
+func NewMux() *Mux {
+	return &Mux{}
+}
+
+// Route adds a rule sending entries accepted by all `filters` to `r`.
+// A rule without filters matches everything.
+func (m *Mux) Route(r Reporter, filters ...Filter) *Mux {
+	m.routes = append(m.routes, route{reporter: r, filters: filters})
+	return m
+}
+
+func (m *Mux) Write(e *Entry, calldepth int) error {
+	var result error
+	matched := false
+
+	for _, r := range m.routes {
+		if !accepts(r.filters, e) {
+			continue
+		}
+		matched = true
+
+		if err := r.reporter.Write(e, calldepth+1); err != nil {
+			result = multierror.Append(result, err)
+		}
+
+		if m.First {
+			break
+		}
+	}
+
+	if !matched && m.Fallback != nil {
+		return m.Fallback.Write(e, calldepth+1)
+	}
+
+	return result
+}
+
+func (m *Mux) wants(e *Entry) bool {
+	for _, r := range m.routes {
+		if accepts(r.filters, e) {
+			return true
+		}
+	}
+	return m.Fallback != nil
+}
+
+// reporters returns the reporters of the rules and the fallback, each
+// once.
+func (m *Mux) reporters() []Reporter {
+	var reporters []Reporter
+	add := func(r Reporter) {
+		if r == nil {
+			return
+		}
+		if reflect.TypeOf(r).Comparable() {
+			for _, seen := range reporters {
+				if seen == r {
+					return
+				}
+			}
+		}
+		reporters = append(reporters, r)
+	}
+
+	for _, r := range m.routes {
+		add(r.reporter)
+	}
+	add(m.Fallback)
+	return reporters
+}
+
+// Flush flushes the reporters that buffer entries.
+func (m *Mux) Flush() error {
+	var result error
+	for _, r := range m.reporters() {
+		if err := flushReporter(r); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// Close closes the reporters that are io.Closers.
+func (m *Mux) Close() error {
+	var result error
+	for _, r := range m.reporters() {
+		if err := closeReporter(r); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// flushReporter flushes `r` when it has a Flush method, wrappers such as
+// Filtered, Mux and RateLimited pass it on.
+func flushReporter(r Reporter) error {
+	if f, ok := r.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// closeReporter closes `r` when it is an io.Closer.
+func closeReporter(r Reporter) error {
+	if c, ok := r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package log_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+func TestFilters(t *testing.T) {
+	e := &log.Entry{Level: log.WARN, Message: "disk almost full", Fields: log.Fields{"disk": "sda", "free": 5}}
+
+	tests := []struct {
+		name   string
+		filter log.Filter
+		want   bool
+	}{
+		{"MinLevel same", log.MinLevel(log.WARN), true},
+		{"MinLevel less severe", log.MinLevel(log.INFO), true},
+		{"MinLevel more severe", log.MinLevel(log.ERROR), false},
+		{"OnlyLevels", log.OnlyLevels(log.ERROR, log.WARN), true},
+		{"OnlyLevels other", log.OnlyLevels(log.INFO), false},
+		{"HasField", log.HasField("disk"), true},
+		{"HasField missing", log.HasField("host"), false},
+		{"FieldEquals", log.FieldEquals("free", 5), true},
+		{"FieldEquals other type", log.FieldEquals("free", int64(5)), false},
+		{"MessageContains", log.MessageContains("full"), true},
+		{"MessageMatches", log.MessageMatches(regexp.MustCompile(`^disk \w+`)), true},
+		{"Not", log.Not(log.HasField("disk")), false},
+		{"All", log.All(log.HasField("disk"), log.MinLevel(log.ERROR)), false},
+		{"All empty", log.All(), true},
+		{"Any", log.Any(log.HasField("host"), log.MinLevel(log.WARN)), true},
+		{"Any empty", log.Any(), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter(e); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	all, warn := logtest.New(), logtest.New()
+	l := log.NewLogger(log.DEBUG, []log.Reporter{all, log.Filtered(warn, log.MinLevel(log.WARN))})
+
+	l.Debug("debug")
+	l.Warn("warn")
+	l.Error("error")
+
+	if all.Len() != 3 {
+		t.Errorf("unfiltered reporter got %d entries, want 3", all.Len())
+	}
+	if warn.Len() != 2 || warn.HasMessage("debug") {
+		t.Errorf("filtered reporter got %v, want warn and error", warn.Entries())
+	}
+}
+
+func TestMuxRoutesToEveryMatch(t *testing.T) {
+	errs, db, rest := logtest.New(), logtest.New(), logtest.New()
+	m := log.NewMux().
+		Route(errs, log.MinLevel(log.ERROR)).
+		Route(db, log.FieldEquals("component", "db"))
+	m.Fallback = rest
+	l := log.NewLogger(log.DEBUG, []log.Reporter{m})
+
+	l.WithField("component", "db").Error("db error")
+	l.WithField("component", "db").Info("db info")
+	l.Info("other")
+
+	if errs.Len() != 1 || !errs.HasMessage("db error") {
+		t.Errorf("error route got %v", errs.Entries())
+	}
+	if db.Len() != 2 {
+		t.Errorf("db route got %v, want both db entries", db.Entries())
+	}
+	if rest.Len() != 1 || !rest.HasMessage("other") {
+		t.Errorf("fallback got %v, want only the unmatched entry", rest.Entries())
+	}
+}
+
+func TestMuxFirst(t *testing.T) {
+	first, second := logtest.New(), logtest.New()
+	m := log.NewMux().Route(first, log.MinLevel(log.ERROR)).Route(second)
+	m.First = true
+	l := log.NewLogger(log.DEBUG, []log.Reporter{m})
+
+	l.Error("error")
+	l.Info("info")
+
+	if first.Len() != 1 || !first.HasMessage("error") {
+		t.Errorf("first route got %v", first.Entries())
+	}
+	if second.Len() != 1 || !second.HasMessage("info") {
+		t.Errorf("second route got %v, want only the entry the first skipped", second.Entries())
+	}
+}
+
+// failing is a reporter that fails every write.
+type failing struct{}
+
+func (failing) Write(e *log.Entry, calldepth int) error {
+	return errors.New("failed")
+}
+
+func TestMuxKeepsWritingAfterAnError(t *testing.T) {
+	r := logtest.New()
+	m := log.NewMux().Route(failing{}).Route(r)
+
+	if err := m.Write(&log.Entry{Level: log.INFO, Message: "entry"}, 0); err == nil {
+		t.Error("the error of the first route was lost")
+	}
+	if r.Len() != 1 {
+		t.Error("the second route was skipped after the first failed")
+	}
+}
+
+// flushCloser counts the calls to Flush and Close.
+type flushCloser struct {
+	*logtest.Recorder
+	flushed, closed int
+}
+
+func (f *flushCloser) Flush() error {
+	f.flushed++
+	return nil
+}
+
+func (f *flushCloser) Close() error {
+	f.closed++
+	return nil
+}
+
+func TestMuxFlushAndCloseOnce(t *testing.T) {
+	r := &flushCloser{Recorder: logtest.New()}
+	m := log.NewMux().Route(r, log.MinLevel(log.ERROR)).Route(log.Filtered(r, log.HasField("db")))
+	m.Fallback = r
+	l := log.NewLogger(log.DEBUG, []log.Reporter{m})
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the Filtered wrapper is a different reporter, so r is reached twice
+	if r.flushed != 2 || r.closed != 2 {
+		t.Errorf("flushed %d and closed %d times, want 2", r.flushed, r.closed)
+	}
+}
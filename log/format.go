@@ -7,6 +7,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -32,13 +33,57 @@ type Formatter interface {
 	Finalize(map[string]interface{}) (string, error)
 }
 
+// formatter renders entries with a template. The template is analysed
+// when parsed, Format only computes the data fields it reads and simple
+// templates skip text/template altogether, see compile.
 type formatter struct {
-	format *template.Template
+	format   *template.Template
+	usage    *usage
+	segments []segment
+	compiled bool
+	caller   bool
+	funcName bool
 }
 
 func (f *formatter) Format(e *Entry, calldepth int) map[string]interface{} {
+	return f.data(e, calldepth+1, false)
+}
+
+// data returns the data of `e` for the template, only the fields the
+// template reads unless `full` is set.
+func (f *formatter) data(e *Entry, calldepth int, full bool) map[string]interface{} {
 	calldepth = calldepth + 1
-	pc, file, line, ok := runtime.Caller(calldepth)
+
+	format := make(map[string]interface{}, 16)
+	set := func(name string, value interface{}) {
+		if full || f.usage.uses(name) {
+			format[name] = value
+		}
+	}
+
+	set("PID", pid)
+	set("Timestamp", e.Timestamp)
+	set("Level", e.Level)
+	set("LevelTitle", Levels[e.Level].Name)
+	set("LevelColor", Levels[e.Level].Color)
+	set("Program", program)
+	set("Message", e.Message)
+	set("Calldepth", calldepth)
+	set("Entry", e)
+
+	if !full && !f.caller {
+		return format
+	}
+
+	frame, ok := e.Caller()
+	if !ok {
+		var pc uintptr
+		pc, frame.File, frame.Line, ok = runtime.Caller(calldepth)
+		if fn := runtime.FuncForPC(pc); ok && fn != nil {
+			frame.Function = fn.Name()
+		}
+	}
+	file, line := frame.File, frame.Line
 
 	// Short and long file processing
 	shortf := file
@@ -49,74 +94,106 @@ func (f *formatter) Format(e *Entry, calldepth int) map[string]interface{} {
 	} else {
 		shortf = filepath.Base(file)
 	}
-	shortfile := fmt.Sprintf("%s:%d", filepath.Base(shortf), line)
-	longfile := fmt.Sprintf("%s:%d", file, line)
+	set("ShortFile", shortf+":"+strconv.Itoa(line))
+	set("LongFile", file+":"+strconv.Itoa(line))
+
+	if !full && !f.funcName {
+		return format
+	}
 
 	// Package and func name processing
 	longpkg := "???"
 	shortpkg := "???"
 	longfunc := "???"
 	shortfunc := "???"
-	if ok {
-		if f := runtime.FuncForPC(pc); f != nil {
-			longpkg = formatFuncName("longpkg", f.Name())
-			shortpkg = formatFuncName("shortpkg", f.Name())
-			longfunc = formatFuncName("longfunc", f.Name())
-			shortfunc = formatFuncName("shortfunc", f.Name())
-		}
+	if ok && frame.Function != "" {
+		longpkg = formatFuncName("longpkg", frame.Function)
+		shortpkg = formatFuncName("shortpkg", frame.Function)
+		longfunc = formatFuncName("longfunc", frame.Function)
+		shortfunc = formatFuncName("shortfunc", frame.Function)
 	}
+	set("LongPkg", longpkg)
+	set("ShortPkg", shortpkg)
+	set("LongFunc", longfunc)
+	set("ShortFunc", shortfunc)
 
-	format := map[string]interface{}{
-		"PID":        pid,
-		"Timestamp":  e.Timestamp,
-		"Level":      e.Level,
-		"LevelTitle": Levels[e.Level].Name,
-		"LevelColor": Levels[e.Level].Color,
-		"Program":    program,
-		"Message":    e.Message,
-		"LongFile":   longfile,
-		"ShortFile":  shortfile,
-		"LongPkg":    longpkg,
-		"ShortPkg":   shortpkg,
-		"LongFunc":   longfunc,
-		"ShortFunc":  shortfunc,
-		"Calldepth":  calldepth,
-	}
 	return format
 }
 
 func (f *formatter) Finalize(data map[string]interface{}) (string, error) {
-	var b bytes.Buffer
-	err := f.format.ExecuteTemplate(&b, "formatter", data)
+	b := bufPool.Get().(*bytes.Buffer)
+	b.Reset()
+	defer bufPool.Put(b)
+
+	var err error
+	if f.compiled {
+		err = execute(b, f.segments, data)
+	} else {
+		err = f.format.ExecuteTemplate(b, "formatter", data)
+	}
 	if err != nil {
 		return "", err
 	}
 	return b.String(), nil
 }
 
-func NewStringFormatter(format string) (Formatter, error) {
-	tmpl := template.New("formatter")
+// formatData returns every data field `f` provides for `e`, as hooks
+// see them in Entry.Formatted, even the ones its template doesn't read.
+func formatData(f Formatter, e *Entry, calldepth int) map[string]interface{} {
+	if ff, ok := f.(*formatter); ok {
+		return ff.data(e, calldepth+1, true)
+	}
+	return f.Format(e, calldepth+1)
+}
+
+// Uses reports whether the template reads the data field `name`.
+func (f *formatter) Uses(name string) bool {
+	return f.usage.uses(name)
+}
+
+// FormatUses reports whether `f` reads the data field `name`, so
+// reporters can skip computing fields they add to the data of Format.
+// It is true when `f` can't tell.
+func FormatUses(f Formatter, name string) bool {
+	if u, ok := f.(interface{ Uses(string) bool }); ok {
+		return u.Uses(name)
+	}
+	return true
+}
 
-	funcs := template.FuncMap{
-		"formatCallpath":    formatCallpath,
-		"formatColor":       formatColor,
-		"formatColorString": formatColorString,
-		"formatTime":        formatTime,
+// NewStringFormatter creates a formatter from a text/template format.
+// Besides the fields of the entry, such as .Message, .Level and .Entry,
+// the format can use the functions listed in funcs.go and those added
+// with WithFuncs.
+func NewStringFormatter(format string, opts ...FormatterOption) (Formatter, error) {
+	custom := template.FuncMap{}
+	for _, opt := range opts {
+		opt(custom)
 	}
 
-	tmpl = tmpl.Funcs(funcs)
+	tmpl := template.New("formatter")
+	tmpl = tmpl.Funcs(templateFuncs()).Funcs(custom)
 
 	tmpl, err := tmpl.Parse(format)
 	if err != nil {
 		return nil, err
 	}
-	return &formatter{tmpl}, nil
+
+	f := &formatter{
+		format: tmpl,
+		usage:  analyse(tmpl.Tree),
+	}
+	f.caller = f.usage.usesAny(callerFields)
+	f.funcName = f.usage.usesAny(funcFields)
+	f.segments, f.compiled = compile(tmpl.Tree, custom)
+
+	return f, nil
 }
 
 // MustStringFormatter is equivalent to NewStringFormatter with a call to panic
 // on error.
-func MustStringFormatter(format string) Formatter {
-	f, err := NewStringFormatter(format)
+func MustStringFormatter(format string, opts ...FormatterOption) Formatter {
+	f, err := NewStringFormatter(format, opts...)
 	if err != nil {
 		panic("Failed to initialized formatter: " + err.Error())
 	}
@@ -186,45 +263,12 @@ func formatColor(color aurora.Color, v ...interface{}) string {
 	return aurora.Colorize(fmt.Sprint(v...), color).String()
 }
 
+// formatColorString colours `v` with the named colour, names are joined
+// with a "+" such as "redfg+bold". Unknown names are ignored.
 func formatColorString(color string, v ...interface{}) string {
 	var c aurora.Color
-	switch strings.ToLower(color) {
-	case "blackfg":
-		c = aurora.BlackFg
-	case "redfg":
-		c = aurora.RedFg
-	case "greenfg":
-		c = aurora.GreenFg
-	case "brownfg":
-		c = aurora.BrownFg
-	case "bluefg":
-		c = aurora.BlueFg
-	case "magentafg":
-		c = aurora.MagentaFg
-	case "cyanfg":
-		c = aurora.CyanFg
-	case "grayfg":
-		c = aurora.GrayFg
-	case "blackbg":
-		c = aurora.BlackBg
-	case "redbg":
-		c = aurora.RedBg
-	case "greenbg":
-		c = aurora.GreenBg
-	case "brownbg":
-		c = aurora.BrownBg
-	case "bluebg":
-		c = aurora.BlueBg
-	case "magentabg":
-		c = aurora.MagentaBg
-	case "cyanbg":
-		c = aurora.CyanBg
-	case "graybg":
-		c = aurora.GrayBg
-	case "bold":
-		c = aurora.BoldFm
-	case "inverse":
-		c = aurora.InverseFm
+	for _, name := range strings.Split(strings.ToLower(color), "+") {
+		c |= colorNames[strings.TrimSpace(name)]
 	}
 	return formatColor(c, v...)
 }
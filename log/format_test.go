@@ -0,0 +1,105 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/handlers/file"
+)
+
+// discard formats entries with its formatter and drops the result.
+type discard struct {
+	formatter log.Formatter
+}
+
+func (d discard) Write(e *log.Entry, calldepth int) error {
+	_, err := d.formatter.Finalize(d.formatter.Format(e, calldepth+1))
+	return err
+}
+
+func TestHookFormattedData(t *testing.T) {
+	var formatted map[string]interface{}
+	l := log.NewLogger(log.DEBUG, []log.Reporter{discard{log.DefaultFormatter}})
+	l.AddHook(log.HookFunc(func(e *log.Entry) error {
+		formatted = e.Formatted
+		return nil
+	}))
+
+	l.Info("hello")
+
+	for _, name := range []string{"Message", "LevelTitle", "Timestamp", "ShortFile", "LongFunc", "PID", "Program"} {
+		if _, ok := formatted[name]; !ok {
+			t.Errorf("Formatted has no %s, got %v", name, formatted)
+		}
+	}
+	if formatted["LevelTitle"] != "Info" {
+		t.Errorf("LevelTitle = %v, want Info", formatted["LevelTitle"])
+	}
+}
+
+func TestFormatOnlyUsedFields(t *testing.T) {
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Message = "hello"
+
+	data := log.DefaultFormatter.Format(e, 0)
+	if len(data) != 1 || data["Message"] != "hello" {
+		t.Errorf("Format = %v, want only the message", data)
+	}
+}
+
+func benchmarkFormat(b *testing.B, f log.Formatter) {
+	l := log.NewLogger(log.DEBUG, []log.Reporter{discard{f}})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithField("n", i).Info("benchmark entry")
+	}
+}
+
+func BenchmarkFormatMessage(b *testing.B) {
+	benchmarkFormat(b, log.DefaultFormatter)
+}
+
+func BenchmarkFormatFileLayout(b *testing.B) {
+	benchmarkFormat(b, log.MustStringFormatter(`[{{formatTime .Timestamp "2006-01-02 15:04:05"}}] [{{ .ShortFile }}] {{ .LevelTitle }}{{"\t"}}> {{ .Message }}`))
+}
+
+func BenchmarkFormatFancy(b *testing.B) {
+	benchmarkFormat(b, log.FancyFormatter)
+}
+
+func BenchmarkFormatCallerFuncs(b *testing.B) {
+	benchmarkFormat(b, log.MustStringFormatter(`{{ .ShortPkg }}.{{ .ShortFunc }} {{ pad 5 .LevelTitle }} {{ .Message }}`))
+}
+
+func BenchmarkDisabledLevel(b *testing.B) {
+	l := log.NewLogger(log.INFO, []log.Reporter{discard{log.DefaultFormatter}})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debug("benchmark entry")
+	}
+}
+
+func BenchmarkFileReporter(b *testing.B) {
+	dir, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := file.New(file.WithFolder(dir), file.WithFilename("bench.log"), file.WithBuffer(64<<10, 0))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	l := log.NewLogger(log.DEBUG, []log.Reporter{f})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithField("n", i).Info("benchmark entry")
+	}
+}
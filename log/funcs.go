@@ -0,0 +1,213 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// FormatterOption adds to the functions NewStringFormatter provides.
+type FormatterOption func(funcs template.FuncMap)
+
+// WithFuncs adds `funcs` to the functions a format can use, replacing
+// the built-in ones of the same name.
+func WithFuncs(funcs template.FuncMap) FormatterOption {
+	return func(custom template.FuncMap) {
+		for name, fn := range funcs {
+			custom[name] = fn
+		}
+	}
+}
+
+var (
+	// startTime is when the process started, for sinceStart and relative.
+	startTime = time.Now()
+
+	hostname = func() string {
+		name, err := os.Hostname()
+		if err != nil {
+			return "???"
+		}
+		return name
+	}()
+)
+
+// colorNames are the colours formatColorString knows.
+var colorNames = map[string]aurora.Color{
+	"blackfg":   aurora.BlackFg,
+	"redfg":     aurora.RedFg,
+	"greenfg":   aurora.GreenFg,
+	"brownfg":   aurora.BrownFg,
+	"yellowfg":  aurora.BrownFg,
+	"bluefg":    aurora.BlueFg,
+	"magentafg": aurora.MagentaFg,
+	"cyanfg":    aurora.CyanFg,
+	"grayfg":    aurora.GrayFg,
+	"blackbg":   aurora.BlackBg,
+	"redbg":     aurora.RedBg,
+	"greenbg":   aurora.GreenBg,
+	"brownbg":   aurora.BrownBg,
+	"yellowbg":  aurora.BrownBg,
+	"bluebg":    aurora.BlueBg,
+	"magentabg": aurora.MagentaBg,
+	"cyanbg":    aurora.CyanBg,
+	"graybg":    aurora.GrayBg,
+	"bold":      aurora.BoldFm,
+	"inverse":   aurora.InverseFm,
+}
+
+// templateFuncs returns the functions every format can use:
+//
+//	formatColor color v...           colour v with an aurora.Color such as .LevelColor
+//	formatColorString name v...      colour v by name, "redfg", "bold", "cyanfg+bold"
+//	levelColor level v...            colour v with the colour of level
+//	colorIf level min name v...      colour v by name when level is min or more severe
+//	formatTime t layout              format t with a time layout
+//	sinceStart t                     time.Duration between the process start and t
+//	relative t                       whole seconds since the process start, "0042"
+//	formatCallpath calldepth depth   the functions leading to the call
+//	pad n v                          v left aligned in n columns
+//	padLeft n v                      v right aligned in n columns
+//	center n v                       v centered in n columns
+//	truncate n v                     v cut to n columns, ending in "…" when cut
+//	upper v, lower v                 change the case of v
+//	field fields name                the field called name, nil when missing
+//	default def v                    def when v is nil or empty, v otherwise
+//	json v                           v encoded as JSON
+//	logfmt fields                    fields as sorted " key=value" pairs
+//	hostname                         the host name
+//	goroutine                        the ID of the goroutine formatting the entry
+//
+// Width functions count runes, pad before colouring.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"formatCallpath":    formatCallpath,
+		"formatColor":       formatColor,
+		"formatColorString": formatColorString,
+		"formatTime":        formatTime,
+
+		"levelColor": formatLevelColor,
+		"colorIf":    formatColorIf,
+		"sinceStart": formatSinceStart,
+		"relative":   formatRelative,
+
+		"pad":      formatPad,
+		"padLeft":  formatPadLeft,
+		"center":   formatCenter,
+		"truncate": formatTruncate,
+		"upper":    func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+		"lower":    func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+
+		"field":   formatField,
+		"default": formatDefault,
+		"json":    formatJSON,
+		"logfmt":  formatLogfmt,
+
+		"hostname":  func() string { return hostname },
+		"goroutine": goroutineID,
+	}
+}
+
+func formatLevelColor(level Level, v ...interface{}) string {
+	return formatColor(Levels[level].Color, v...)
+}
+
+func formatColorIf(level, min Level, color string, v ...interface{}) string {
+	if level > min {
+		return fmt.Sprint(v...)
+	}
+	return formatColorString(color, v...)
+}
+
+func formatSinceStart(t time.Time) time.Duration {
+	return t.Sub(startTime).Truncate(time.Millisecond)
+}
+
+func formatRelative(t time.Time) string {
+	return fmt.Sprintf("%04d", int(t.Sub(startTime)/time.Second))
+}
+
+func formatPad(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	if w := utf8.RuneCountInString(s); w < n {
+		s += strings.Repeat(" ", n-w)
+	}
+	return s
+}
+
+func formatPadLeft(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	if w := utf8.RuneCountInString(s); w < n {
+		s = strings.Repeat(" ", n-w) + s
+	}
+	return s
+}
+
+func formatCenter(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	w := utf8.RuneCountInString(s)
+	if w >= n {
+		return s
+	}
+	left := (n - w) / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", n-w-left)
+}
+
+func formatTruncate(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+func formatField(fields Fields, name string) interface{} {
+	return fields[name]
+}
+
+func formatDefault(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		if rv.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return def
+		}
+	}
+	return v
+}
+
+func formatJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func formatLogfmt(fields Fields) string {
+	var b bytes.Buffer
+	for _, name := range fields.Names() {
+		value := fmt.Sprint(fields[name])
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", name, value)
+	}
+	return b.String()
+}
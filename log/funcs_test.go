@@ -0,0 +1,109 @@
+package log_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// render formats `e` with the format `format`.
+func render(t *testing.T, format string, e *log.Entry, opts ...log.FormatterOption) string {
+	t.Helper()
+
+	f, err := log.NewStringFormatter(format, opts...)
+	if err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	s, err := f.Finalize(f.Format(e, 0))
+	if err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	return s
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Level = log.WARN
+	e.Message = "Disk"
+	e.Timestamp = time.Date(2026, 10, 18, 9, 5, 0, 0, time.UTC)
+	e.Fields = log.Fields{"id": 7, "msg": "a b", "empty": ""}
+
+	tests := map[string]string{
+		`{{ pad 6 .Message }}|`:                           "Disk  |",
+		`{{ padLeft 6 .Message }}|`:                       "  Disk|",
+		`{{ center 8 .Message }}|`:                        "  Disk  |",
+		`{{ pad 2 .Message }}|`:                           "Disk|",
+		`{{ truncate 3 .Message }}`:                       "Di…",
+		`{{ truncate 4 .Message }}`:                       "Disk",
+		`{{ pad 4 (truncate 3 "äöüß") }}|`:                "äö… |",
+		`{{ upper .Message }} {{ lower .Message }}`:       "DISK disk",
+		`{{ field .Entry.Fields "id" }}`:                  "7",
+		`{{ default "-" (field .Entry.Fields "user") }}`:  "-",
+		`{{ default "-" (field .Entry.Fields "empty") }}`: "-",
+		`{{ default "-" (field .Entry.Fields "id") }}`:    "7",
+		`{{ json .Entry.Fields.id }}`:                     "7",
+		`{{ json .Message }}`:                             `"Disk"`,
+		`{{ logfmt .Entry.Fields }}`:                      ` empty= id=7 msg="a b"`,
+		`{{ formatTime .Timestamp "15:04" }}`:             "09:05",
+		`{{ colorIf .Level .Level "redfg" .Message }}`:    "\x1b[31mDisk\x1b[0m",
+	}
+	for format, want := range tests {
+		if got := render(t, format, e); got != want {
+			t.Errorf("%s = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestTemplateColorFuncs(t *testing.T) {
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Level = log.ERROR
+	e.Message = "failed"
+
+	for _, format := range []string{
+		`{{ formatColorString "cyanfg+bold" .Message }}`,
+		`{{ formatColor .LevelColor .Message }}`,
+		`{{ levelColor .Level .Message }}`,
+	} {
+		got := render(t, format, e)
+		if !strings.HasPrefix(got, "\x1b[") || !strings.Contains(got, "failed") {
+			t.Errorf("%s = %q, want a coloured message", format, got)
+		}
+	}
+}
+
+func TestTemplateProcessFuncs(t *testing.T) {
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Timestamp = time.Now()
+
+	name, _ := os.Hostname()
+	if got := render(t, `{{ hostname }}`, e); name != "" && got != name {
+		t.Errorf("hostname = %q, want %q", got, name)
+	}
+	if got := render(t, `{{ relative .Timestamp }}`, e); len(got) != 4 {
+		t.Errorf("relative = %q, want four digits", got)
+	}
+	if got := render(t, `{{ goroutine }}`, e); got == "" || got == "0" {
+		t.Errorf("goroutine = %q, want the id of the test goroutine", got)
+	}
+}
+
+func TestWithFuncs(t *testing.T) {
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Message = "hello"
+
+	funcs := template.FuncMap{
+		"shout": func(s string) string { return s + "!" },
+		"upper": func(s string) string { return "replaced" },
+	}
+	if got := render(t, `{{ shout .Message }} {{ upper .Message }}`, e, log.WithFuncs(funcs)); got != "hello! replaced" {
+		t.Errorf("got %q, want the custom functions", got)
+	}
+
+	if _, err := log.NewStringFormatter(`{{ shout .Message }}`); err == nil {
+		t.Error("a format using an unknown function was accepted")
+	}
+}
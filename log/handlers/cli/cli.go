@@ -5,21 +5,14 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
+	"sync"
 
 	"github.com/keiwi/utils/log"
-	"github.com/kyokomi/emoji"
 	"github.com/logrusorgru/aurora"
 	"github.com/mattn/go-colorable"
 )
 
-var icons = map[log.Level]string{
-	log.FATAL: ":shit:",
-	log.ERROR: ":no_entry_sign:",
-	log.WARN:  ":x:",
-	log.INFO:  ":+1:",
-	log.DEBUG: ":mag:",
-}
-
 // field used for sorting.
 type field struct {
 	Name  string
@@ -34,7 +27,13 @@ func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byName) Less(i, j int) bool { return a[i].Name < a[j].Name }
 
 func NewCli() *Cli {
-	return &Cli{colorable.NewColorableStdout(), CliFormatter}
+	console := colorable.NewColorableStdout()
+	return &Cli{
+		Writer:    console,
+		Formatter: CliFormatter,
+		Palette:   EmojiPalette,
+		console:   console,
+	}
 }
 
 var (
@@ -45,21 +44,36 @@ var (
 type Cli struct {
 	Writer    io.Writer
 	Formatter log.Formatter
+
+	// Color is when colour codes are written, to a terminal by default.
+	Color ColorMode
+
+	// Palette is the colour and icon of every level.
+	Palette Palette
+
+	// Collapse folds consecutive identical entries into one line with a
+	// repeat counter, updated in place when Writer is a TTY.
+	Collapse bool
+
+	mu      sync.Mutex
+	console io.Writer
+	tty     bool
+	color   bool
+	repeat  repeat
 }
 
 func (c *Cli) Write(e *log.Entry, calldepth int) error {
-	fields := make([]field, len(e.Fields))
-	i := 0
-	for k, v := range e.Fields {
-		fields[i] = field{k, v}
-		i++
-	}
-	sort.Sort(byName(fields))
+	style := c.Palette.style(e.Level)
 
 	data := c.Formatter.Format(e, calldepth+1)
-	data["LevelIcon"] = emoji.Sprintf(icons[e.Level])
-	data["Fields"] = fields
-	data["ParsedFields"] = parseEntry(e)
+	data["LevelColor"] = style.Color
+	data["LevelIcon"] = style.Icon
+	if log.FormatUses(c.Formatter, "Fields") {
+		data["Fields"] = sortedFields(e)
+	}
+	if log.FormatUses(c.Formatter, "ParsedFields") {
+		data["ParsedFields"] = parseEntry(e)
+	}
 
 	e.Formatted = data
 
@@ -68,10 +82,66 @@ func (c *Cli) Write(e *log.Entry, calldepth int) error {
 		return err
 	}
 
-	_, err = fmt.Fprintln(colorable.NewColorableStdout(), msg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.detect()
+	msg = c.colorize(msg)
+
+	if c.Collapse {
+		return c.writeCollapsed(c.Writer, e, msg)
+	}
+
+	_, err = fmt.Fprintln(c.Writer, msg)
 	return err
 }
 
+// detect checks whether Writer is a terminal and is coloured, Writer may
+// change between writes.
+func (c *Cli) detect() {
+	c.tty = terminal(c.Writer, c.console)
+	c.color = colored(c.Color, c.tty)
+}
+
+// colorize returns `s` without colour codes when output is not coloured.
+func (c *Cli) colorize(s string) string {
+	if c.color {
+		return s
+	}
+	return stripColor(s)
+}
+
+// Flush writes the summary of a pending run of collapsed entries.
+func (c *Cli) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.detect()
+	return c.flushRepeat(c.Writer)
+}
+
+// sortedFields returns the fields of `e` sorted by name.
+func sortedFields(e *log.Entry) []field {
+	fields := make([]field, 0, len(e.Fields))
+	for k, v := range e.Fields {
+		fields = append(fields, field{k, v})
+	}
+	sort.Sort(byName(fields))
+	return fields
+}
+
+// formatValue formats stacks indented below the field and cause chains
+// from outermost to innermost.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case log.Stack:
+		return "\n" + v.Indent("\t\t")
+	case []string:
+		return strings.Join(v, " <- ")
+	}
+	return fmt.Sprint(v)
+}
+
 func parseEntry(e *log.Entry) string {
 	var b bytes.Buffer
 
@@ -92,7 +162,7 @@ func parseEntry(e *log.Entry) string {
 	}
 
 	for _, v := range fields {
-		field := fmt.Sprintf("%s=%v", v.Name, v.Value)
+		field := fmt.Sprintf("%s=%s", v.Name, formatValue(v.Value))
 		if v.Name == "callstack" {
 			field = aurora.Colorize(field, aurora.BlackFg).Bold().String()
 		} else {
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+var testStack = log.Stack{
+	{Function: "main.run", File: "/src/main.go", Line: 12},
+	{Function: "main.main", File: "/src/main.go", Line: 5},
+}
+
+func TestWriteStackAndCauses(t *testing.T) {
+	var b bytes.Buffer
+	c, err := New(WithWriter(&b), WithColor(ColorNever), WithFormat("{{ .Message }}{{ .ParsedFields }}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Write(&log.Entry{
+		Level:   log.ERROR,
+		Message: "failed",
+		Fields: log.Fields{
+			"error":     "outer: inner",
+			"causes":    []string{"middle", "inner"},
+			"callstack": testStack,
+		},
+	}, 0)
+
+	want := "failed \n" +
+		"\tcauses=middle <- inner \n" +
+		"\terror=outer: inner \n" +
+		"\tcallstack=\n" +
+		"\t\tmain.run\n\t\t\t/src/main.go:12\n" +
+		"\t\tmain.main\n\t\t\t/src/main.go:5\n\n"
+	if got := b.String(); got != want {
+		t.Errorf("wrote\n%q\nwant\n%q", got, want)
+	}
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Error("ColorNever wrote colour codes")
+	}
+}
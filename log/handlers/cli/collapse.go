@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/keiwi/utils/log"
+	"github.com/logrusorgru/aurora"
+)
+
+// repeat tracks a run of identical entries for Cli.Collapse.
+type repeat struct {
+	key   string
+	count int
+	first time.Time
+	last  time.Time
+	lines int
+}
+
+// suffix returns the repeat counter shown after a collapsed entry.
+func (r *repeat) suffix() string {
+	return aurora.Colorize(fmt.Sprintf("(x%d, first %s, last %s)",
+		r.count, r.first.Format("15:04:05"), r.last.Format("15:04:05")), aurora.BlackFg).Bold().String()
+}
+
+// collapseKey returns the key identical entries share.
+func collapseKey(e *log.Entry) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d %s", e.Level, e.Message)
+
+	for _, name := range e.Fields.Names() {
+		fmt.Fprintf(&b, " %s=%v", name, e.Fields[name])
+	}
+	return b.String()
+}
+
+// writeCollapsed writes `msg`, collapsing it into the previous line when
+// `e` repeats the previous entry. On a TTY the previous line is rewritten
+// in place, otherwise the run is summarized once it ends.
+func (c *Cli) writeCollapsed(out io.Writer, e *log.Entry, msg string) error {
+	key := collapseKey(e)
+
+	if c.repeat.count > 0 && key == c.repeat.key {
+		c.repeat.count++
+		c.repeat.last = e.Timestamp
+
+		if !c.tty {
+			return nil
+		}
+
+		if _, err := fmt.Fprintf(out, "\x1b[%dA\x1b[J", c.repeat.lines); err != nil {
+			return err
+		}
+		return c.writeLines(out, msg+" "+c.colorize(c.repeat.suffix()))
+	}
+
+	if err := c.flushRepeat(out); err != nil {
+		return err
+	}
+
+	c.repeat = repeat{
+		key:   key,
+		count: 1,
+		first: e.Timestamp,
+		last:  e.Timestamp,
+	}
+	return c.writeLines(out, msg)
+}
+
+// writeLines writes `msg` and remembers how many lines it took.
+func (c *Cli) writeLines(out io.Writer, msg string) error {
+	c.repeat.lines = strings.Count(msg, "\n") + 1
+	_, err := fmt.Fprintln(out, msg)
+	return err
+}
+
+// flushRepeat writes the summary of a collapsed run when not on a TTY.
+func (c *Cli) flushRepeat(out io.Writer) error {
+	if c.tty || c.repeat.count < 2 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(out, "last message repeated %d more times %s\n", c.repeat.count-1, c.colorize(c.repeat.suffix()))
+	c.repeat.count = 1
+	return err
+}
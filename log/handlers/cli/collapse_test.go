@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// newCollapsing creates a collapsing Cli writing messages to `b`, on a
+// TTY when `tty` is set.
+func newCollapsing(t *testing.T, b *bytes.Buffer, tty bool) *Cli {
+	t.Helper()
+
+	c, err := New(WithWriter(b), WithColor(ColorNever), WithFormat("{{ .Message }}"), WithCollapse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty {
+		// the writer NewCli wraps stdout in counts as a terminal
+		c.console = b
+	}
+	return c
+}
+
+var collapseTime = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func writeMessages(c *Cli, messages ...string) {
+	for i, msg := range messages {
+		c.Write(&log.Entry{
+			Level:     log.INFO,
+			Message:   msg,
+			Timestamp: collapseTime.Add(time.Duration(i) * time.Second),
+		}, 0)
+	}
+}
+
+func TestCollapseSummarizesOffTTY(t *testing.T) {
+	var b bytes.Buffer
+	c := newCollapsing(t, &b, false)
+
+	writeMessages(c, "a", "a", "a", "b", "b")
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "a\n" +
+		"last message repeated 2 more times (x3, first 12:00:00, last 12:00:02)\n" +
+		"b\n" +
+		"last message repeated 1 more times (x2, first 12:00:03, last 12:00:04)\n"
+	if got := b.String(); got != want {
+		t.Errorf("wrote\n%q\nwant\n%q", got, want)
+	}
+
+	// a flushed run is not summarized again
+	b.Reset()
+	c.Flush()
+	if b.Len() != 0 {
+		t.Errorf("second Flush wrote %q", b.String())
+	}
+}
+
+func TestCollapseRewritesOnTTY(t *testing.T) {
+	var b bytes.Buffer
+	c := newCollapsing(t, &b, true)
+
+	writeMessages(c, "a", "a", "a", "b")
+	c.Flush()
+
+	want := "a\n" +
+		"\x1b[1A\x1b[Ja (x2, first 12:00:00, last 12:00:01)\n" +
+		"\x1b[1A\x1b[Ja (x3, first 12:00:00, last 12:00:02)\n" +
+		"b\n"
+	if got := b.String(); got != want {
+		t.Errorf("wrote\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCollapseComparesFields(t *testing.T) {
+	var b bytes.Buffer
+	c := newCollapsing(t, &b, false)
+
+	for _, id := range []int{1, 1, 2} {
+		c.Write(&log.Entry{Level: log.INFO, Message: "a", Fields: log.Fields{"id": id}, Timestamp: collapseTime}, 0)
+	}
+	c.Write(&log.Entry{Level: log.WARN, Message: "a", Fields: log.Fields{"id": 2}, Timestamp: collapseTime}, 0)
+
+	want := "a\n" +
+		"last message repeated 1 more times (x2, first 12:00:00, last 12:00:00)\n" +
+		"a\n" +
+		"a\n"
+	if got := b.String(); got != want {
+		t.Errorf("wrote\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestNoCollapseByDefault(t *testing.T) {
+	var b bytes.Buffer
+	c, err := New(WithWriter(&b), WithColor(ColorNever), WithFormat("{{ .Message }}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeMessages(c, "a", "a")
+	if got := b.String(); got != "a\na\n" {
+		t.Errorf("wrote %q, want both entries", got)
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/keiwi/utils/log"
+	"github.com/kyokomi/emoji"
+	"github.com/logrusorgru/aurora"
+	"github.com/mattn/go-isatty"
+)
+
+// ColorMode is when a Cli writes colour codes.
+type ColorMode int
+
+const (
+	// ColorAuto colours output to a terminal, unless NO_COLOR is set.
+	// FORCE_COLOR colours any output.
+	ColorAuto ColorMode = iota
+	// ColorAlways colours any output.
+	ColorAlways
+	// ColorNever writes plain text.
+	ColorNever
+)
+
+// colorModes are the names of the colour modes.
+var colorModes = map[string]ColorMode{
+	"auto":   ColorAuto,
+	"always": ColorAlways,
+	"never":  ColorNever,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the names
+// "auto", "always" and "never".
+func (m *ColorMode) UnmarshalText(text []byte) error {
+	mode, ok := colorModes[strings.ToLower(string(text))]
+	if !ok {
+		return fmt.Errorf("unknown color mode %q", text)
+	}
+	*m = mode
+	return nil
+}
+
+// Style is how a level is shown.
+type Style struct {
+	Color aurora.Color
+	Icon  string
+}
+
+// Palette is the style of every level, levels missing from it use the
+// colour of log.Levels and no icon.
+type Palette map[log.Level]Style
+
+var (
+	// EmojiPalette shows emoji icons, the default.
+	EmojiPalette = Palette{
+		log.FATAL: {Color: log.Levels[log.FATAL].Color, Icon: emoji.Sprint(":shit:")},
+		log.ERROR: {Color: log.Levels[log.ERROR].Color, Icon: emoji.Sprint(":no_entry_sign:")},
+		log.WARN:  {Color: log.Levels[log.WARN].Color, Icon: emoji.Sprint(":x:")},
+		log.INFO:  {Color: log.Levels[log.INFO].Color, Icon: emoji.Sprint(":+1:")},
+		log.DEBUG: {Color: log.Levels[log.DEBUG].Color, Icon: emoji.Sprint(":mag:")},
+	}
+
+	// ASCIIPalette shows icons every terminal and font can display.
+	ASCIIPalette = Palette{
+		log.FATAL: {Color: log.Levels[log.FATAL].Color, Icon: "[!!] "},
+		log.ERROR: {Color: log.Levels[log.ERROR].Color, Icon: "[x] "},
+		log.WARN:  {Color: log.Levels[log.WARN].Color, Icon: "[!] "},
+		log.INFO:  {Color: log.Levels[log.INFO].Color, Icon: "[i] "},
+		log.DEBUG: {Color: log.Levels[log.DEBUG].Color, Icon: "[.] "},
+	}
+
+	// PlainPalette shows no icons.
+	PlainPalette = Palette{}
+)
+
+// palettes are the palettes selectable by name.
+var palettes = map[string]Palette{
+	"emoji": EmojiPalette,
+	"ascii": ASCIIPalette,
+	"none":  PlainPalette,
+}
+
+// style returns the style of `level`.
+func (p Palette) style(level log.Level) Style {
+	if s, ok := p[level]; ok {
+		return s
+	}
+	return Style{Color: log.Levels[level].Color}
+}
+
+// terminal reports whether `w` is a terminal, `console` is the writer
+// NewCli wrapped stdout in.
+func terminal(w, console io.Writer) bool {
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	return console != nil && w == console
+}
+
+// colored reports whether output is coloured in `mode`, `tty` tells if
+// it goes to a terminal. See https://no-color.org and FORCE_COLOR.
+func colored(mode ColorMode, tty bool) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok && v != "0" && v != "false" {
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return tty
+}
+
+var ansiCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stripColor removes colour codes from `s`.
+func stripColor(s string) string {
+	return ansiCodes.ReplaceAllString(s, "")
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+// setEnv sets or with an empty value unsets the variables in `env` and
+// restores them when the test ends.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		if v == "" {
+			os.Unsetenv(k)
+		} else {
+			os.Setenv(k, v)
+		}
+
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestColored(t *testing.T) {
+	tests := []struct {
+		mode    ColorMode
+		noColor string
+		force   string
+		tty     bool
+		want    bool
+	}{
+		{ColorAuto, "", "", true, true},
+		{ColorAuto, "", "", false, false},
+		{ColorAuto, "1", "", true, false},
+		{ColorAuto, "", "1", false, true},
+		{ColorAuto, "1", "1", false, true},
+		{ColorAuto, "", "0", false, false},
+		{ColorAuto, "", "false", true, true},
+		{ColorAlways, "1", "", false, true},
+		{ColorNever, "", "1", true, false},
+	}
+
+	for _, tt := range tests {
+		setEnv(t, map[string]string{"NO_COLOR": tt.noColor, "FORCE_COLOR": tt.force})
+		if got := colored(tt.mode, tt.tty); got != tt.want {
+			t.Errorf("colored(%d, %v) with NO_COLOR=%q FORCE_COLOR=%q = %v, want %v",
+				tt.mode, tt.tty, tt.noColor, tt.force, got, tt.want)
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	var b bytes.Buffer
+	if terminal(&b, nil) {
+		t.Error("a buffer is a terminal")
+	}
+	if !terminal(&b, &b) {
+		t.Error("the console writer is not a terminal")
+	}
+
+	f, err := ioutil.TempFile("", "cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if terminal(f, nil) {
+		t.Error("a regular file is a terminal")
+	}
+}
+
+func TestWriteColors(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		mode  ColorMode
+		color bool
+	}{
+		{"auto off a TTY", map[string]string{"NO_COLOR": "", "FORCE_COLOR": ""}, ColorAuto, false},
+		{"FORCE_COLOR", map[string]string{"NO_COLOR": "", "FORCE_COLOR": "1"}, ColorAuto, true},
+		{"always", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": ""}, ColorAlways, true},
+	}
+
+	for _, tt := range tests {
+		setEnv(t, tt.env)
+
+		var b bytes.Buffer
+		c, err := New(WithWriter(&b), WithColor(tt.mode), WithFormat(`{{ formatColor .LevelColor .Message }}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Write(&log.Entry{Level: log.ERROR, Message: "failed"}, 0)
+
+		if got := strings.Contains(b.String(), "\x1b["); got != tt.color {
+			t.Errorf("%s: wrote %q, want colour %v", tt.name, b.String(), tt.color)
+		}
+		if !strings.Contains(b.String(), "failed") {
+			t.Errorf("%s: wrote %q, want the message", tt.name, b.String())
+		}
+	}
+}
+
+func TestColorModeOption(t *testing.T) {
+	c, err := FromOptions(log.Options{"color": "never", "icons": "ascii"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Color != ColorNever || c.Palette[log.WARN].Icon != "[!] " {
+		t.Errorf("color %d and palette %v, want never and ascii", c.Color, c.Palette)
+	}
+
+	if _, err := FromOptions(log.Options{"color": "sometimes"}); err == nil {
+		t.Error("unknown color mode accepted")
+	}
+	if _, err := FromOptions(log.Options{"icons": "pictures"}); err == nil {
+		t.Error("unknown icons accepted")
+	}
+}
+
+func TestWriterChange(t *testing.T) {
+	var first, second bytes.Buffer
+	c, err := New(WithWriter(&first), WithFormat("{{ .Message }}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Write(&log.Entry{Level: log.INFO, Message: "first"}, 0)
+	c.Writer = &second
+	c.Write(&log.Entry{Level: log.INFO, Message: "second"}, 0)
+
+	if first.String() != "first\n" || second.String() != "second\n" {
+		t.Errorf("wrote %q and %q, want one entry each", first.String(), second.String())
+	}
+}
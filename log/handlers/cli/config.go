@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/keiwi/utils/log"
+	"github.com/mattn/go-colorable"
+)
+
+// Config configures a Cli.
+type Config struct {
+	// Output is "stdout" or "stderr", stdout when empty.
+	Output string
+	// Writer replaces Output when set.
+	Writer io.Writer `json:"-"`
+	// Format is the template of an entry, CliFormatter when empty.
+	Format string
+	// Collapse folds consecutive identical entries, see Cli.Collapse.
+	Collapse bool
+	// Color is "auto", "always" or "never", see ColorMode.
+	Color ColorMode
+	// Icons is "emoji", "ascii" or "none", emoji when empty.
+	Icons string
+	// Palette replaces Icons when set.
+	Palette Palette `json:"-"`
+}
+
+// Validate reports the first invalid setting of the config.
+func (c *Config) Validate() error {
+	switch c.Output {
+	case "", "stdout", "stderr":
+	default:
+		return fmt.Errorf("cli logger: unknown output %q", c.Output)
+	}
+	if _, ok := palettes[c.Icons]; c.Icons != "" && !ok {
+		return fmt.Errorf("cli logger: unknown icons %q", c.Icons)
+	}
+	return nil
+}
+
+// Option changes the configuration of New.
+type Option func(*Config)
+
+// New creates a cli reporter changed by `opts`.
+func New(opts ...Option) (*Cli, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return newCli(&config)
+}
+
+func init() {
+	log.RegisterReporter("cli", func(o log.Options) (log.Reporter, error) {
+		r, err := FromOptions(o)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+}
+
+// FromOptions creates a cli reporter from declarative options such as
+// {"output": "stderr", "collapse": true}.
+func FromOptions(o log.Options) (*Cli, error) {
+	var config Config
+	if err := o.Decode(&config); err != nil {
+		return nil, fmt.Errorf("cli logger: %v", err)
+	}
+	return newCli(&config)
+}
+
+// WithConfig replaces the whole configuration.
+func WithConfig(config Config) Option {
+	return func(c *Config) { *c = config }
+}
+
+// WithOutput writes to "stdout" or "stderr".
+func WithOutput(output string) Option {
+	return func(c *Config) { c.Output = output }
+}
+
+// WithWriter writes to `w`.
+func WithWriter(w io.Writer) Option {
+	return func(c *Config) { c.Writer = w }
+}
+
+// WithFormat sets the template of an entry.
+func WithFormat(format string) Option {
+	return func(c *Config) { c.Format = format }
+}
+
+// WithColor sets when colour codes are written.
+func WithColor(mode ColorMode) Option {
+	return func(c *Config) { c.Color = mode }
+}
+
+// WithIcons shows "emoji", "ascii" or "none" icons.
+func WithIcons(icons string) Option {
+	return func(c *Config) { c.Icons = icons }
+}
+
+// WithPalette sets the colour and icon of every level.
+func WithPalette(p Palette) Option {
+	return func(c *Config) { c.Palette = p }
+}
+
+// WithCollapse folds consecutive identical entries.
+func WithCollapse() Option {
+	return func(c *Config) { c.Collapse = true }
+}
+
+func newCli(config *Config) (*Cli, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := NewCli()
+	c.Collapse = config.Collapse
+	c.Color = config.Color
+
+	switch {
+	case config.Writer != nil:
+		c.Writer = config.Writer
+	case config.Output == "stderr":
+		c.console = colorable.NewColorableStderr()
+		c.Writer = c.console
+	}
+
+	switch {
+	case config.Palette != nil:
+		c.Palette = config.Palette
+	case config.Icons != "":
+		c.Palette = palettes[config.Icons]
+	}
+
+	if config.Format != "" {
+		formatter, err := log.NewStringFormatter(config.Format)
+		if err != nil {
+			return nil, err
+		}
+		c.Formatter = formatter
+	}
+
+	return c, nil
+}
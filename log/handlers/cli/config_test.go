@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestValidate(t *testing.T) {
+	bad := []Config{
+		{Output: "stdlog"},
+		{Icons: "pictures"},
+	}
+	for _, config := range bad {
+		if err := config.Validate(); err == nil {
+			t.Errorf("config %+v accepted", config)
+		}
+		if _, err := New(WithConfig(config)); err == nil {
+			t.Errorf("New accepted %+v", config)
+		}
+	}
+
+	if _, err := New(WithFormat("{{ .Message")); err == nil {
+		t.Error("invalid format accepted")
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Writer != c.console || c.Formatter != CliFormatter || c.Color != ColorAuto || c.Collapse {
+		t.Errorf("defaults %+v, want NewCli", c)
+	}
+
+	c, err = New(WithOutput("stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Writer != c.console {
+		t.Error("stderr is not written through the console writer")
+	}
+
+	var b bytes.Buffer
+	palette := Palette{log.INFO: {Icon: "i "}}
+	c, err = New(WithWriter(&b), WithOutput("stderr"), WithPalette(palette), WithIcons("ascii"), WithCollapse(), WithColor(ColorNever))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Writer != &b || c.Palette[log.INFO].Icon != "i " || !c.Collapse || c.Color != ColorNever {
+		t.Errorf("options %+v, want the writer and palette to win", c)
+	}
+}
+
+func TestFromOptions(t *testing.T) {
+	c, err := FromOptions(log.Options{"output": "stderr", "collapse": "true", "format": "{{ .Message }}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Collapse || c.Formatter == CliFormatter {
+		t.Errorf("options %+v, want collapse and the format", c)
+	}
+
+	bad := []log.Options{
+		{"output": "stdlog"},
+		{"writer": "stdout"},
+		{"collapse": "sometimes"},
+	}
+	for _, o := range bad {
+		if _, err := FromOptions(o); err == nil {
+			t.Errorf("options %v accepted", o)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// Config configures a File, see DefaultConfig for the defaults.
+type Config struct {
+	// Filename is the file name pattern, %date% and strftime verbs such
+	// as %Y, %m, %d, %H and %V are replaced, see expandPattern.
+	Filename string
+	Folder   string
+	MaxSize  int64
+	// MaxLines rotates a file at this many lines, the lines already in
+	// it are counted when it is opened, also after an eviction or Reopen.
+	MaxLines int64
+
+	// Format is the template of an entry, FileFormatter when empty.
+	Format string
+
+	// Symlink is kept pointing at the current file when set.
+	Symlink string
+
+	// FileMode is the mode of new files, 0644 when 0.
+	FileMode os.FileMode
+	// DirMode is the mode of new directories, 0755 when 0.
+	DirMode os.FileMode
+	// Owner and Group own new files when set, as names or numeric IDs.
+	Owner string
+	Group string
+
+	// LevelFiles writes entries at or above a level to an extra file as
+	// well, {log.ERROR: "errors.log"} keeps the errors apart.
+	LevelFiles map[log.Level]string
+	// SplitField writes entries to one file per value of the field, the
+	// Filename and LevelFiles must contain %field% to place the value.
+	// Characters other than letters, digits, "-" and "_" are replaced by
+	// "_" in the value, dots included. Entries without the field use
+	// "none", its file is only created once such an entry is written.
+	SplitField string
+	// MaxOpenFiles is the number of SplitField files kept open, the
+	// least recently used one is closed when another is needed. 64
+	// when 0.
+	MaxOpenFiles int
+
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+	// MaxAge removes rotated files older than it, checked on rotation and
+	// at least every hour.
+	MaxAge time.Duration
+	// MaxTotalSize caps the size of the current and rotated files
+	// together, the oldest rotated files are removed first.
+	MaxTotalSize int64
+	// Compress rotated files in the background, CompressGzip or
+	// CompressZstd.
+	Compress string
+
+	// BufferSize buffers writes in memory when set, see SyncPolicy for
+	// what that means for durability.
+	BufferSize int
+	// FlushInterval writes the buffer to the file this often.
+	FlushInterval time.Duration
+	// Sync is when written entries are committed to stable storage.
+	Sync SyncPolicy
+	// SyncEvery is the number of entries between syncs for SyncEntries.
+	SyncEvery int
+
+	// OnError is called with every error of the handler, including
+	// errors of background work such as compression, so a full disk or
+	// a permission problem does not go unnoticed. It must not call
+	// methods of the File. Background errors are printed when nil.
+	OnError func(error)
+
+	// ReopenOnSignal reopens the file on SIGHUP, as sent by logrotate.
+	ReopenOnSignal bool
+	// WatchInterval checks this often whether the file was moved, removed
+	// or truncated by another process and reopens it.
+	WatchInterval time.Duration
+	// CopyTruncate leaves rotation to logrotate's copytruncate: the
+	// handler never rotates itself and follows truncations, checking
+	// every second unless WatchInterval is set.
+	CopyTruncate bool
+}
+
+// DefaultConfig returns the default configuration, a daily file in the
+// working directory.
+func DefaultConfig() Config {
+	return Config{
+		Filename: "%date%.log",
+		Folder:   ".",
+		FileMode: 0644,
+		DirMode:  0755,
+	}
+}
+
+// Validate reports the first invalid setting of the config.
+func (c *Config) Validate() error {
+	switch {
+	case c.Filename == "":
+		return fmt.Errorf("file logger: missing Filename")
+	case c.MaxSize < 0 || c.MaxLines < 0 || c.MaxBackups < 0 || c.MaxAge < 0 || c.MaxTotalSize < 0 || c.MaxOpenFiles < 0:
+		return fmt.Errorf("file logger: limits must not be negative")
+	case c.BufferSize < 0 || c.FlushInterval < 0 || c.WatchInterval < 0:
+		return fmt.Errorf("file logger: buffer and intervals must not be negative")
+	case c.Sync < SyncNever || c.Sync > SyncAlways:
+		return fmt.Errorf("file logger: unknown sync policy %d", c.Sync)
+	case c.Sync == SyncEntries && c.SyncEvery <= 0:
+		return fmt.Errorf("file logger: SyncEntries needs SyncEvery")
+	case c.SplitField != "" && !strings.Contains(c.Filename, fieldVerb):
+		return fmt.Errorf("file logger: SplitField needs %s in the Filename", fieldVerb)
+	}
+
+	if _, ok := compressExt[c.Compress]; !ok && c.Compress != CompressNone {
+		return fmt.Errorf("file logger: unknown compression %q", c.Compress)
+	}
+	return nil
+}
+
+// Option changes the configuration of New.
+type Option func(*Config)
+
+// New creates a file reporter from the DefaultConfig changed by `opts`.
+func New(opts ...Option) (*File, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return NewFile(&config)
+}
+
+func init() {
+	log.RegisterReporter("file", func(o log.Options) (log.Reporter, error) {
+		r, err := FromOptions(o)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+}
+
+// FromOptions creates a file reporter from the DefaultConfig changed by
+// declarative options such as {"filename": "app.log", "max_age": "168h"}.
+func FromOptions(o log.Options) (*File, error) {
+	config := DefaultConfig()
+	if err := o.Decode(&config); err != nil {
+		return nil, fmt.Errorf("file logger: %v", err)
+	}
+	return NewFile(&config)
+}
+
+// WithConfig replaces the whole configuration.
+func WithConfig(config Config) Option {
+	return func(c *Config) { *c = config }
+}
+
+// WithFilename sets the file name pattern.
+func WithFilename(filename string) Option {
+	return func(c *Config) { c.Filename = filename }
+}
+
+// WithFolder sets the folder of the files.
+func WithFolder(folder string) Option {
+	return func(c *Config) { c.Folder = folder }
+}
+
+// WithFormat sets the template of an entry.
+func WithFormat(format string) Option {
+	return func(c *Config) { c.Format = format }
+}
+
+// WithMaxSize rotates files at `size` bytes.
+func WithMaxSize(size int64) Option {
+	return func(c *Config) { c.MaxSize = size }
+}
+
+// WithMaxLines rotates files at `lines` lines, counting the lines of an
+// existing file when it is opened.
+func WithMaxLines(lines int64) Option {
+	return func(c *Config) { c.MaxLines = lines }
+}
+
+// WithSymlink keeps a symlink pointing at the current file.
+func WithSymlink(name string) Option {
+	return func(c *Config) { c.Symlink = name }
+}
+
+// WithRetention keeps at most `backups` rotated files, none older than
+// `age` and at most `total` bytes together. Zero values are no limit.
+func WithRetention(backups int, age time.Duration, total int64) Option {
+	return func(c *Config) {
+		c.MaxBackups = backups
+		c.MaxAge = age
+		c.MaxTotalSize = total
+	}
+}
+
+// WithCompress compresses rotated files, CompressGzip or CompressZstd.
+func WithCompress(format string) Option {
+	return func(c *Config) { c.Compress = format }
+}
+
+// WithBuffer buffers `size` bytes in memory, flushed every `interval`.
+func WithBuffer(size int, interval time.Duration) Option {
+	return func(c *Config) {
+		c.BufferSize = size
+		c.FlushInterval = interval
+	}
+}
+
+// WithSync sets the sync policy, `every` is used by SyncEntries.
+func WithSync(policy SyncPolicy, every int) Option {
+	return func(c *Config) {
+		c.Sync = policy
+		c.SyncEvery = every
+	}
+}
+
+// WithOnError sets the error callback.
+func WithOnError(fn func(error)) Option {
+	return func(c *Config) { c.OnError = fn }
+}
+
+// WithReopenOnSignal reopens the files on SIGHUP.
+func WithReopenOnSignal() Option {
+	return func(c *Config) { c.ReopenOnSignal = true }
+}
+
+// WithWatch checks every `interval` whether the files were moved,
+// removed or truncated.
+func WithWatch(interval time.Duration) Option {
+	return func(c *Config) { c.WatchInterval = interval }
+}
+
+// WithCopyTruncate leaves rotation to logrotate's copytruncate.
+func WithCopyTruncate() Option {
+	return func(c *Config) { c.CopyTruncate = true }
+}
+
+// WithModes sets the mode of new files and directories.
+func WithModes(file, dir os.FileMode) Option {
+	return func(c *Config) {
+		c.FileMode = file
+		c.DirMode = dir
+	}
+}
+
+// WithOwner sets the owner and group of new files.
+func WithOwner(owner, group string) Option {
+	return func(c *Config) {
+		c.Owner = owner
+		c.Group = group
+	}
+}
+
+// WithLevelFile also writes entries at or above `level` to `filename`.
+func WithLevelFile(level log.Level, filename string) Option {
+	return func(c *Config) {
+		if c.LevelFiles == nil {
+			c.LevelFiles = map[log.Level]string{}
+		}
+		c.LevelFiles[level] = filename
+	}
+}
+
+// WithSplitField writes one file per value of `field`.
+func WithSplitField(field string) Option {
+	return func(c *Config) { c.SplitField = field }
+}
+
+// WithMaxOpenFiles keeps at most `n` SplitField files open.
+func WithMaxOpenFiles(n int) Option {
+	return func(c *Config) { c.MaxOpenFiles = n }
+}
@@ -0,0 +1,175 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]func(c *Config){
+		"no filename":      func(c *Config) { c.Filename = "" },
+		"negative size":    func(c *Config) { c.MaxSize = -1 },
+		"negative lines":   func(c *Config) { c.MaxLines = -1 },
+		"negative backups": func(c *Config) { c.MaxBackups = -1 },
+		"negative age":     func(c *Config) { c.MaxAge = -time.Hour },
+		"negative total":   func(c *Config) { c.MaxTotalSize = -1 },
+		"negative files":   func(c *Config) { c.MaxOpenFiles = -1 },
+		"negative buffer":  func(c *Config) { c.BufferSize = -1 },
+		"negative flush":   func(c *Config) { c.FlushInterval = -time.Second },
+		"negative watch":   func(c *Config) { c.WatchInterval = -time.Second },
+		"unknown sync":     func(c *Config) { c.Sync = SyncAlways + 1 },
+		"sync entries":     func(c *Config) { c.Sync = SyncEntries },
+		"split no verb":    func(c *Config) { c.SplitField = "client" },
+		"unknown compress": func(c *Config) { c.Compress = "zip" },
+	}
+
+	for name, change := range tests {
+		config := DefaultConfig()
+		change(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: config accepted", name)
+		}
+		if _, err := NewFile(&config); err == nil {
+			t.Errorf("%s: NewFile accepted the config", name)
+		}
+	}
+
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Errorf("DefaultConfig: %v", err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := New(WithFolder(dir), WithFormat("{{ .Message")); err == nil {
+		t.Error("invalid format accepted")
+	}
+	if _, err := New(WithFolder(dir), WithOwner("no-such-user-of-the-tests", "")); err == nil {
+		t.Error("unknown owner accepted")
+	}
+
+	// a file where the folder should be
+	blocked := filepath.Join(dir, "blocked")
+	if err := ioutil.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithFolder(blocked)); err == nil {
+		t.Error("unusable folder accepted")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var config Config
+	onError := func(error) {}
+	opts := []Option{
+		WithFilename("app.log"),
+		WithFolder("/var/log/app"),
+		WithMaxSize(10 << 20),
+		WithMaxLines(1000),
+		WithRetention(5, time.Hour, 1<<30),
+		WithCompress(CompressGzip),
+		WithBuffer(4096, time.Second),
+		WithSync(SyncEntries, 10),
+		WithOnError(onError),
+		WithReopenOnSignal(),
+		WithWatch(time.Minute),
+		WithModes(0600, 0700),
+		WithOwner("app", "adm"),
+		WithLevelFile(log.ERROR, "errors.log"),
+		WithSplitField("client"),
+		WithMaxOpenFiles(8),
+		WithSymlink("current"),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.Filename != "app.log" || config.Folder != "/var/log/app" || config.MaxSize != 10<<20 || config.MaxLines != 1000 {
+		t.Errorf("rotation config %+v", config)
+	}
+	if config.MaxBackups != 5 || config.MaxAge != time.Hour || config.MaxTotalSize != 1<<30 || config.Compress != CompressGzip {
+		t.Errorf("retention config %+v", config)
+	}
+	if config.BufferSize != 4096 || config.FlushInterval != time.Second || config.Sync != SyncEntries || config.SyncEvery != 10 || config.OnError == nil {
+		t.Errorf("durability config %+v", config)
+	}
+	if !config.ReopenOnSignal || config.WatchInterval != time.Minute || config.FileMode != 0600 || config.DirMode != 0700 {
+		t.Errorf("file config %+v", config)
+	}
+	if config.Owner != "app" || config.Group != "adm" || config.LevelFiles[log.ERROR] != "errors.log" {
+		t.Errorf("owner and level files %+v", config)
+	}
+	if config.SplitField != "client" || config.MaxOpenFiles != 8 || config.Symlink != "current" {
+		t.Errorf("split config %+v", config)
+	}
+
+	WithCopyTruncate()(&config)
+	WithConfig(Config{Filename: "other.log"})(&config)
+	if config.Filename != "other.log" || config.CopyTruncate || config.MaxSize != 0 {
+		t.Errorf("WithConfig kept %+v", config)
+	}
+}
+
+func TestFromOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := FromOptions(log.Options{
+		"folder":         dir,
+		"filename":       "app.log",
+		"max_size":       "10MB",
+		"max_lines":      "1000",
+		"max_age":        "168h",
+		"max_backups":    7,
+		"compress":       "zstd",
+		"sync":           "on_error",
+		"file_mode":      "0600",
+		"level_files":    "error=errors.log",
+		"buffer_size":    "4KB",
+		"flush_interval": "1s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c := f.config
+	if c.MaxSize != 10<<20 || c.MaxLines != 1000 || c.MaxAge != 168*time.Hour || c.MaxBackups != 7 || c.Compress != CompressZstd {
+		t.Errorf("config %+v", c)
+	}
+	if c.Sync != SyncOnError || c.FileMode != 0600 || c.LevelFiles[log.ERROR] != "errors.log" || c.BufferSize != 4096 || c.FlushInterval != time.Second {
+		t.Errorf("config %+v", c)
+	}
+	// defaults not given as options are kept
+	if c.DirMode != 0755 {
+		t.Errorf("DirMode = %o, want the default 0755", c.DirMode)
+	}
+
+	bad := []log.Options{
+		{"max_lines": "1KB"},
+		{"sync": "sometimes"},
+		{"compress": "zip"},
+		{"colour": "never"},
+	}
+	for _, o := range bad {
+		o["folder"] = dir
+		if f, err := FromOptions(o); err == nil {
+			f.Close()
+			t.Errorf("options %v accepted", o)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// SyncPolicy is when written entries are committed to stable storage.
+//
+// Without a buffer every entry is handed to the operating system as it is
+// written, it survives a crash of the process but not of the machine
+// until it is synced. With a buffer, entries also wait in memory until
+// the buffer is full, FlushInterval passes, Flush or Close is called or
+// the policy syncs, and a crash of the process loses them.
+//
+// FATAL entries are always flushed and synced, the process exits right
+// after writing them.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves syncing to the operating system.
+	SyncNever SyncPolicy = iota
+	// SyncEntries syncs every SyncEvery entries.
+	SyncEntries
+	// SyncOnError syncs after every ERROR and FATAL entry, so the
+	// entries leading up to an error are not lost.
+	SyncOnError
+	// SyncAlways syncs after every entry, the safest and slowest.
+	SyncAlways
+)
+
+// commit flushes and syncs after writing an entry at `level`, as the
+// sync policy demands.
+func (w *writer) commit(level log.Level) error {
+	w.unsynced++
+
+	doSync := level == log.FATAL
+	switch w.syncPolicy {
+	case SyncEntries:
+		doSync = doSync || w.syncEvery <= 0 || w.unsynced >= w.syncEvery
+	case SyncOnError:
+		doSync = doSync || level <= log.ERROR
+	case SyncAlways:
+		doSync = true
+	}
+
+	if !doSync {
+		return nil
+	}
+	return w.sync()
+}
+
+// sync writes the buffer to the file and syncs it.
+func (w *writer) sync() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	w.unsynced = 0
+	return w.file.Sync()
+}
+
+// flush writes the buffer to the file.
+func (w *writer) flush() error {
+	if w.buf == nil {
+		return nil
+	}
+	return w.buf.Flush()
+}
+
+// flushEvery flushes the buffer every flushInterval until `done` is closed.
+func (w *writer) flushEvery(done chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.Lock()
+				var err error
+				if !w.stopped {
+					err = w.flush()
+				}
+				w.Unlock()
+
+				if err != nil {
+					w.report(fmt.Errorf("flushing log file: %v", err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// syncPolicies are the names of the sync policies.
+var syncPolicies = map[string]SyncPolicy{
+	"never":    SyncNever,
+	"entries":  SyncEntries,
+	"on_error": SyncOnError,
+	"always":   SyncAlways,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the names
+// "never", "entries", "on_error" and "always".
+func (p *SyncPolicy) UnmarshalText(text []byte) error {
+	policy, ok := syncPolicies[strings.ToLower(string(text))]
+	if !ok {
+		return fmt.Errorf("unknown sync policy %q", text)
+	}
+	*p = policy
+	return nil
+}
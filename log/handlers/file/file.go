@@ -3,10 +3,11 @@ package file
 import (
 	"bytes"
 	"fmt"
-	slog "log"
 	"sort"
+	"strings"
 	"sync"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/keiwi/utils/log"
 	"github.com/kyokomi/emoji"
 )
@@ -16,28 +17,38 @@ var (
 	FileFormatter = log.MustStringFormatter(`[{{formatTime .Timestamp "2006-01-02 15:04:05"}}] [{{ .ShortFile }}] {{ .LevelIcon }} {{ .LevelTitle }}{{"\t"}}> {{ .Message }}{{ .ParsedFields }}`)
 )
 
-func NewFile(config *Config) *File {
-	w := writer{
-		Mutex:      new(sync.Mutex),
-		fileFormat: config.Filename,
-		folder:     config.Folder,
-		maxSize:    config.MaxSize,
-		maxLines:   config.MaxLines,
+// NewFile creates a file reporter from `config` and opens its file.
+func NewFile(config *Config) (*File, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
-	err := w.Init()
-	if err != nil {
-		slog.Fatalf("Error when initializing file logger: %v", err.Error())
+	f := &File{
+		Formatter: FileFormatter,
+		config:    *config,
+		writers:   map[string]*writer{},
 	}
 
-	return &File{writer: w, Formatter: FileFormatter}
-}
+	if config.Format != "" {
+		formatter, err := log.NewStringFormatter(config.Format)
+		if err != nil {
+			return nil, err
+		}
+		f.Formatter = formatter
+	}
+
+	// SplitField files are opened for the first entry of each value
+	if config.SplitField != "" {
+		return f, nil
+	}
+
+	w, err := f.writerFor(config.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file: %v", err)
+	}
+	f.main = w
 
-type Config struct {
-	Filename string
-	Folder   string
-	MaxSize  int64
-	MaxLines int64
+	return f, nil
 }
 
 var icons = map[log.Level]string{
@@ -61,24 +72,31 @@ func (a byName) Len() int           { return len(a) }
 func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byName) Less(i, j int) bool { return a[i].Name < a[j].Name }
 
+// File is a reporter writing entries to rotating log files. Every file
+// of a File, including LevelFiles and SplitField files, is rotated and
+// retained with the same Config.
 type File struct {
-	writer    writer
 	Formatter log.Formatter
+
+	config  Config
+	mu      sync.Mutex
+	main    *writer
+	writers map[string]*writer
+	clock   uint64
+	closed  bool
 }
 
 func (c *File) Write(e *log.Entry, calldepth int) error {
-	fields := make([]field, len(e.Fields))
-	i := 0
-	for k, v := range e.Fields {
-		fields[i] = field{k, v}
-		i++
-	}
-	sort.Sort(byName(fields))
-
 	data := c.Formatter.Format(e, calldepth+1)
-	data["LevelIcon"] = emoji.Sprintf(icons[e.Level])
-	data["Fields"] = fields
-	data["ParsedFields"] = parseEntry(e)
+	if log.FormatUses(c.Formatter, "LevelIcon") {
+		data["LevelIcon"] = emoji.Sprintf(icons[e.Level])
+	}
+	if log.FormatUses(c.Formatter, "Fields") {
+		data["Fields"] = sortedFields(e)
+	}
+	if log.FormatUses(c.Formatter, "ParsedFields") {
+		data["ParsedFields"] = parseEntry(e)
+	}
 
 	e.Formatted = data
 
@@ -87,8 +105,51 @@ func (c *File) Write(e *log.Entry, calldepth int) error {
 		return err
 	}
 
-	c.writer.Write([]byte(fmt.Sprintln(msg)))
-	return nil
+	message := []byte(fmt.Sprintln(msg))
+
+	var result error
+	for _, name := range c.targets(e) {
+		w, err := c.writerFor(name)
+		if err == nil {
+			err = w.Write(message, e.Level)
+		}
+		if err == ErrClosed {
+			// the writer was closed as the least recently used one
+			// after writerFor returned it, open it again
+			if w, err = c.writerFor(name); err == nil {
+				err = w.Write(message, e.Level)
+			}
+		}
+		if err == ErrClosed {
+			return err
+		}
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// sortedFields returns the fields of `e` sorted by name.
+func sortedFields(e *log.Entry) []field {
+	fields := make([]field, 0, len(e.Fields))
+	for k, v := range e.Fields {
+		fields = append(fields, field{k, v})
+	}
+	sort.Sort(byName(fields))
+	return fields
+}
+
+// formatValue formats stacks indented below the field and cause chains
+// from outermost to innermost.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case log.Stack:
+		return "\n" + v.Indent("\t\t")
+	case []string:
+		return strings.Join(v, " <- ")
+	}
+	return fmt.Sprint(v)
 }
 
 func parseEntry(e *log.Entry) string {
@@ -111,7 +172,7 @@ func parseEntry(e *log.Entry) string {
 	}
 
 	for _, v := range fields {
-		field := fmt.Sprintf("%s=%v", v.Name, v.Value)
+		field := fmt.Sprintf("%s=%s", v.Name, formatValue(v.Value))
 		if len(fields) > 2 {
 			field = "\n\t" + field
 		} else {
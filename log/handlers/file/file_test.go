@@ -0,0 +1,241 @@
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// newTestFile creates a File writing only the message to "test.log" in
+// a temporary folder.
+func newTestFile(t *testing.T, opts ...Option) (*File, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	opts = append([]Option{WithFolder(dir), WithFilename("test.log"), WithFormat("{{ .Message }}")}, opts...)
+	f, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, filepath.Join(dir, "test.log")
+}
+
+func write(t *testing.T, f *File, level log.Level, msg string) {
+	t.Helper()
+
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	e.Level = level
+	e.Message = msg
+	e.Timestamp = time.Now()
+	if err := f.Write(e, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lines(t *testing.T, path string) []string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(b))
+}
+
+func TestSyncPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SyncPolicy
+		every  int
+		levels []log.Level
+		// want is the number of lines on disk after each entry
+		want []int
+	}{
+		{"never", SyncNever, 0, []log.Level{log.INFO, log.ERROR, log.INFO}, []int{0, 0, 0}},
+		{"entries", SyncEntries, 2, []log.Level{log.INFO, log.INFO, log.INFO, log.INFO}, []int{0, 2, 2, 4}},
+		{"on error", SyncOnError, 0, []log.Level{log.INFO, log.WARN, log.ERROR, log.INFO}, []int{0, 0, 3, 3}},
+		{"always", SyncAlways, 0, []log.Level{log.DEBUG, log.INFO}, []int{1, 2}},
+		{"fatal", SyncNever, 0, []log.Level{log.INFO, log.FATAL}, []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, path := newTestFile(t, WithBuffer(1<<16, 0), WithSync(tt.policy, tt.every))
+			defer f.Close()
+
+			for i, level := range tt.levels {
+				write(t, f, level, "entry")
+				if got := len(lines(t, path)); got != tt.want[i] {
+					t.Errorf("after entry %d: %d lines on disk, want %d", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUnbufferedWritesReachTheFile(t *testing.T) {
+	f, path := newTestFile(t)
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	if got := lines(t, path); len(got) != 1 || got[0] != "one" {
+		t.Errorf("file holds %q, want [one]", got)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	f, path := newTestFile(t, WithBuffer(1<<16, 0))
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	write(t, f, log.INFO, "two")
+	if got := lines(t, path); len(got) != 0 {
+		t.Fatalf("file holds %q before Flush, want nothing", got)
+	}
+
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(t, path); len(got) != 2 {
+		t.Errorf("file holds %q after Flush, want both entries", got)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	f, path := newTestFile(t, WithBuffer(1<<16, 5*time.Millisecond))
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+
+	deadline := time.Now().Add(time.Second)
+	for len(lines(t, path)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("buffer was not flushed within a second")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClose(t *testing.T) {
+	f, path := newTestFile(t, WithBuffer(1<<16, 0))
+
+	write(t, f, log.INFO, "one")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(t, path); len(got) != 1 {
+		t.Errorf("file holds %q after Close, want the buffered entry", got)
+	}
+
+	e := log.NewEntry(log.NewLogger(log.DEBUG, nil))
+	if err := f.Write(e, 0); err != ErrClosed {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+	if err := f.Flush(); err == nil {
+		t.Error("Flush after Close succeeded")
+	}
+	if err := f.Close(); err == nil {
+		t.Error("second Close succeeded")
+	}
+}
+
+func TestBufferedWatchCountsLines(t *testing.T) {
+	f, _ := newTestFile(t, WithBuffer(1<<16, 0), WithWatch(time.Millisecond), WithMaxLines(5))
+	defer f.Close()
+
+	for i := 0; i < 30; i++ {
+		write(t, f, log.INFO, "entry")
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	s := f.Stats()
+	if s.Rotations != 5 || s.Lines != 5 {
+		t.Errorf("got %d rotations and %d lines, want 5 and 5", s.Rotations, s.Lines)
+	}
+}
+
+func TestCopyTruncate(t *testing.T) {
+	f, path := newTestFile(t, WithCopyTruncate(), WithWatch(time.Millisecond))
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	write(t, f, log.INFO, "two")
+
+	if s := f.Stats(); s.Lines != 1 || s.Bytes != int64(len("two\n")) {
+		t.Errorf("got %d lines and %d bytes after truncation, want 1 and 4", s.Lines, s.Bytes)
+	}
+}
+
+// TestErrorsWithRedirectedStdLog checks that errors reported while the
+// Logger is locked don't go through the standard logger, which may be
+// redirected back into the same Logger.
+func TestErrorsWithRedirectedStdLog(t *testing.T) {
+	var printed bytes.Buffer
+	stderr.SetOutput(&printed)
+	defer stderr.SetOutput(os.Stderr)
+
+	f, _ := newTestFile(t, WithFilename("app-%field%.log"), WithSplitField("client"), WithMaxOpenFiles(1))
+	l := log.NewLogger(log.DEBUG, []log.Reporter{f})
+	defer log.RedirectStdLog(l, log.INFO)()
+
+	l.WithField("client", "a").Info("one")
+	// closing the file of "a" makes closing it as the idle file fail
+	f.writers["app-a.log"].file.Close()
+
+	done := make(chan struct{})
+	go func() {
+		l.WithField("client", "b").Info("two")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging deadlocked")
+	}
+	if !strings.Contains(printed.String(), "closing idle log file") {
+		t.Errorf("printed %q, want the error closing the idle file", printed.String())
+	}
+}
+
+func TestWriteStackAndCauses(t *testing.T) {
+	f, path := newTestFile(t, WithFormat("{{ .Message }}{{ .ParsedFields }}"))
+
+	err := f.Write(&log.Entry{
+		Level:   log.ERROR,
+		Message: "failed",
+		Fields: log.Fields{
+			"causes": []string{"middle", "inner"},
+			"callstack": log.Stack{
+				{Function: "main.run", File: "/src/main.go", Line: 12},
+			},
+		},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "failed | causes=middle <- inner | callstack=\n\t\tmain.run\n\t\t\t/src/main.go:12\n"
+	if string(b) != want {
+		t.Errorf("wrote %q, want %q", b, want)
+	}
+}
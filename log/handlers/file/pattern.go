@@ -0,0 +1,153 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expandPattern replaces the verbs in the file name `format` with `t`.
+//
+// Besides %date% (2006-01-02) the strftime verbs %Y, %y, %G, %m, %d, %H,
+// %M, %S, %j, %V, %u, %a and %b are supported, %% is a literal %.
+// %H gives hourly files and %G-W%V weekly ones.
+func expandPattern(format string, t time.Time) string {
+	format = strings.Replace(format, "%date%", "%Y-%m-%d", -1)
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if v, ok := expandVerb(format[i], t); ok {
+			b.WriteString(v)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
+
+// expandVerb returns the value of a single strftime verb.
+func expandVerb(verb byte, t time.Time) (string, bool) {
+	switch verb {
+	case 'Y':
+		return strconv.Itoa(t.Year()), true
+	case 'y':
+		return t.Format("06"), true
+	case 'G':
+		year, _ := t.ISOWeek()
+		return strconv.Itoa(year), true
+	case 'm':
+		return t.Format("01"), true
+	case 'd':
+		return t.Format("02"), true
+	case 'H':
+		return t.Format("15"), true
+	case 'M':
+		return t.Format("04"), true
+	case 'S':
+		return t.Format("05"), true
+	case 'j':
+		return fmt.Sprintf("%03d", t.YearDay()), true
+	case 'V':
+		_, week := t.ISOWeek()
+		return fmt.Sprintf("%02d", week), true
+	case 'u':
+		day := int(t.Weekday())
+		if day == 0 {
+			day = 7
+		}
+		return strconv.Itoa(day), true
+	case 'a':
+		return t.Format("Mon"), true
+	case 'b':
+		return t.Format("Jan"), true
+	case '%':
+		return "%", true
+	}
+	return "", false
+}
+
+// splitExt splits `name` into the part before and the extension,
+// "app.log" gives "app" and ".log".
+func splitExt(name string) (string, string) {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// indexedName returns the backup name for `name` with `index`,
+// "app-2026-10-18.log" and 3 give "app-2026-10-18.3.log".
+func indexedName(name string, index int) string {
+	stem, ext := splitExt(name)
+	return fmt.Sprintf("%s.%d%s", stem, index, ext)
+}
+
+// verbPatterns match what each verb expands to, so the pattern of one
+// file never matches the files of another pattern in the same folder.
+var verbPatterns = map[byte]string{
+	'Y': `\d{4}`,
+	'y': `\d{2}`,
+	'G': `\d{4}`,
+	'm': `\d{2}`,
+	'd': `\d{2}`,
+	'H': `\d{2}`,
+	'M': `\d{2}`,
+	'S': `\d{2}`,
+	'j': `\d{3}`,
+	'V': `\d{2}`,
+	'u': `[1-7]`,
+	'a': `[A-Z][a-z]{2}`,
+	'b': `[A-Z][a-z]{2}`,
+	'%': `%`,
+}
+
+// backupPattern returns a pattern matching every name `format` produces,
+// with an optional backup index and compression extension.
+func backupPattern(format string) *regexp.Regexp {
+	stem, ext := splitExt(filepath.Base(strings.Replace(format, "%date%", "%Y-%m-%d", -1)))
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(stem); i++ {
+		if stem[i] == '%' && i+1 < len(stem) {
+			if p, ok := verbPatterns[stem[i+1]]; ok {
+				b.WriteString(p)
+				i++
+				continue
+			}
+		}
+		b.WriteString(regexp.QuoteMeta(stem[i : i+1]))
+	}
+	b.WriteString(`(\.\d+)?`)
+	b.WriteString(regexp.QuoteMeta(ext))
+	b.WriteString(`(\.gz|\.zst)?$`)
+
+	return regexp.MustCompile(b.String())
+}
+
+// nextIndex returns the first backup index of `name` not used in
+// `names`, compressed backups included.
+func nextIndex(name string, names []string) int {
+	stem, ext := splitExt(name)
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(stem) + `\.(\d+)` + regexp.QuoteMeta(ext) + `(\.gz|\.zst)?$`)
+
+	next := 1
+	for _, n := range names {
+		m := re.FindStringSubmatch(n)
+		if m == nil {
+			continue
+		}
+		if i, err := strconv.Atoi(m[1]); err == nil && i >= next {
+			next = i + 1
+		}
+	}
+	return next
+}
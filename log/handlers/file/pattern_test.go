@@ -0,0 +1,100 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestExpandPattern(t *testing.T) {
+	// a Sunday in ISO week 2 of 2027
+	tm := time.Date(2027, 1, 17, 9, 5, 3, 0, time.UTC)
+
+	tests := map[string]string{
+		"app-%date%.log":     "app-2027-01-17.log",
+		"app-%Y%m%d-%H.log":  "app-20270117-09.log",
+		"%y/%j/%M%S.log":     "27/017/0503.log",
+		"app-%G-W%V-%u.log":  "app-2027-W02-7.log",
+		"%a-%b.log":          "Sun-Jan.log",
+		"100%%-%q-%.log":     "100%-%q-%.log",
+		"app.log":            "app.log",
+		"app-%field%-%d.log": "app-%field%-17.log",
+	}
+	for format, want := range tests {
+		if got := expandPattern(format, tm); got != want {
+			t.Errorf("expandPattern(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestIndexedNames(t *testing.T) {
+	if got := indexedName("app-2026-10-18.log", 3); got != "app-2026-10-18.3.log" {
+		t.Errorf("indexedName = %q", got)
+	}
+	if got := indexedName("app", 1); got != "app.1" {
+		t.Errorf("indexedName without extension = %q", got)
+	}
+
+	names := []string{"app.log", "app.1.log", "app.2.log.gz", "app.4.log.zst", "app.9.txt", "other.7.log"}
+	if got := nextIndex("app.log", names); got != 5 {
+		t.Errorf("nextIndex = %d, want 5 after the compressed app.4.log.zst", got)
+	}
+	if got := nextIndex("new.log", names); got != 1 {
+		t.Errorf("nextIndex of a new file = %d, want 1", got)
+	}
+}
+
+func TestBackupPattern(t *testing.T) {
+	re := backupPattern("logs/app-%date%.log")
+
+	for _, name := range []string{"app-2026-10-18.log", "app-2026-10-18.2.log", "app-2026-10-18.12.log.gz", "app-2026-10-18.1.log.zst"} {
+		if !re.MatchString(name) {
+			t.Errorf("%s does not match", name)
+		}
+	}
+	for _, name := range []string{"app-2026-10-18.log.tmp", "app-error-2026-10-18.log", "app-26-10-18.log", "current", "app-2026-10-18.x.log"} {
+		if re.MatchString(name) {
+			t.Errorf("%s matches", name)
+		}
+	}
+}
+
+func TestRotationNamesAndSymlink(t *testing.T) {
+	f, path := newTestFile(t, WithFilename("app-%Y.log"), WithMaxLines(1), WithSymlink("current"))
+	dir := filepath.Dir(path)
+	year := time.Now().Format("2006")
+
+	for _, msg := range []string{"one", "two", "three"} {
+		write(t, f, log.INFO, msg)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"app-" + year + ".1.log": "one",
+		"app-" + year + ".2.log": "two",
+		"app-" + year + ".log":   "three",
+		"current":                "three",
+	}
+	for name, msg := range want {
+		if got := lines(t, filepath.Join(dir, name)); strings.Join(got, ",") != msg {
+			t.Errorf("%s contains %q, want %s", name, got, msg)
+		}
+	}
+
+	target, err := os.Readlink(filepath.Join(dir, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "app-"+year+".log" {
+		t.Errorf("current points at %q, want the relative name of the live file", target)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "current.tmp")); !os.IsNotExist(err) {
+		t.Errorf("the temporary symlink was left behind: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrClosed is returned when using a File after Close.
+var ErrClosed = errors.New("log file is closed")
+
+// reopen closes the live file and opens it again by name, which picks up
+// a new file after an external rotation moved or removed the old one.
+func (w *writer) reopen() error {
+	if w.stopped {
+		return ErrClosed
+	}
+
+	w.close()
+	return w.openFile()
+}
+
+// watch reopens the live file when it was moved or removed and resets
+// the counters when it was truncated by a copytruncate rotation. It
+// checks at most once per watchInterval.
+func (w *writer) watch() error {
+	if w.watchInterval <= 0 || time.Since(w.lastCheck) < w.watchInterval {
+		return nil
+	}
+	w.lastCheck = time.Now()
+
+	open, err := w.file.Stat()
+	if err != nil {
+		return w.reopen()
+	}
+
+	disk, err := os.Stat(w.filename)
+	if err != nil || !os.SameFile(open, disk) {
+		return w.reopen()
+	}
+
+	// bytes still in the buffer are counted but not on disk yet
+	buffered := int64(0)
+	if w.buf != nil {
+		buffered = int64(w.buf.Buffered())
+	}
+	if open.Size() < w.stats.bytes-buffered {
+		w.stats.bytes = open.Size() + buffered
+		w.stats.lines = 0
+	}
+	return nil
+}
+
+// handleSignals reopens the live file on SIGHUP until `done` is closed.
+func (w *writer) handleSignals(done chan struct{}) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				w.Lock()
+				err := w.reopen()
+				w.unlock()
+
+				if err != nil {
+					w.report(fmt.Errorf("reopening log file: %v", err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Reopen closes the log files and opens them again by name. Call it
+// after an external tool such as logrotate moved the files.
+func (c *File) Reopen() error {
+	return c.each(func(w *writer) error {
+		w.Lock()
+		defer w.unlock()
+
+		return w.reopen()
+	})
+}
+
+// Flush writes buffered entries to the files and commits them to stable
+// storage.
+func (c *File) Flush() error {
+	return c.each(func(w *writer) error {
+		w.Lock()
+		defer w.Unlock()
+
+		if w.stopped {
+			return ErrClosed
+		}
+		return w.sync()
+	})
+}
+
+// Close flushes and closes the log files and stops the background work.
+// Writes after Close return ErrClosed.
+func (c *File) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	return c.each((*writer).shutdown)
+}
+
+// shutdown flushes and closes the writer for good.
+func (w *writer) shutdown() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stopped {
+		return ErrClosed
+	}
+	w.stopped = true
+
+	if w.done != nil {
+		close(w.done)
+	}
+	if w.retention != nil {
+		close(w.retention.rotated)
+	}
+
+	err := w.sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.isClosed = true
+	return err
+}
@@ -0,0 +1,120 @@
+//go:build !windows
+// +build !windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// rotateAway moves the live file like logrotate does.
+func rotateAway(t *testing.T, path string) string {
+	t.Helper()
+
+	moved := path + ".old"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	return moved
+}
+
+// checkReopened checks that "one" stayed in the moved file and "two"
+// went to a new file by the old name.
+func checkReopened(t *testing.T, path, moved string) {
+	t.Helper()
+
+	if got := lines(t, moved); strings.Join(got, ",") != "one" {
+		t.Errorf("the moved file contains %q, want one", got)
+	}
+	if got := lines(t, path); strings.Join(got, ",") != "two" {
+		t.Errorf("the reopened file contains %q, want two", got)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	f, path := newTestFile(t)
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	moved := rotateAway(t, path)
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, log.INFO, "two")
+
+	checkReopened(t, path, moved)
+}
+
+func TestReopenOnSIGHUP(t *testing.T) {
+	f, path := newTestFile(t, WithReopenOnSignal())
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	moved := rotateAway(t, path)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the file was not reopened after SIGHUP")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	write(t, f, log.INFO, "two")
+
+	checkReopened(t, path, moved)
+}
+
+func TestWatchReopensMovedFile(t *testing.T) {
+	f, path := newTestFile(t, WithWatch(time.Millisecond))
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	moved := rotateAway(t, path)
+	time.Sleep(2 * time.Millisecond)
+	write(t, f, log.INFO, "two")
+
+	checkReopened(t, path, moved)
+}
+
+func TestWatchReopensReplacedFile(t *testing.T) {
+	f, path := newTestFile(t, WithWatch(time.Millisecond))
+	defer f.Close()
+
+	write(t, f, log.INFO, "one")
+	moved := rotateAway(t, path)
+	// a new file by the same name has a different inode
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	write(t, f, log.INFO, "two")
+
+	checkReopened(t, path, moved)
+}
+
+func TestReopenAfterClose(t *testing.T) {
+	f, _ := newTestFile(t)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Reopen(); err == nil || !strings.Contains(err.Error(), ErrClosed.Error()) {
+		t.Errorf("Reopen after Close = %v, want ErrClosed", err)
+	}
+	if err := f.Write(&log.Entry{Level: log.INFO, Message: "late"}, 0); err != ErrClosed {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
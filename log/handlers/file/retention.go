@@ -0,0 +1,283 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for rotated files.
+const (
+	CompressNone = ""
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// compressExt maps compression formats to file extensions.
+var compressExt = map[string]string{
+	CompressGzip: ".gz",
+	CompressZstd: ".zst",
+}
+
+// maxSweepInterval is the longest time between two cleanups when
+// MaxAge is set, so backups expire even when the file doesn't rotate.
+const maxSweepInterval = time.Hour
+
+// backup is a rotated log file.
+type backup struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// retention compresses and prunes rotated files in the background.
+type retention struct {
+	folder     string
+	pattern    *regexp.Regexp
+	compress   string
+	maxBackups int
+	maxAge     time.Duration
+	maxTotal   int64
+	uid        int
+	gid        int
+
+	rotated chan string
+	report  func(error)
+	// live returns the current files of every writer of the File, none
+	// of them is a backup even when the pattern matches it.
+	live func() []string
+}
+
+// newRetention returns nil when `config` has no retention options.
+// The backups of the file name pattern `filename` are kept, compressed
+// files are owned by `uid` and `gid`. Cleanup errors are passed to
+// `report`, the files `live` returns are never touched.
+func newRetention(config *Config, filename string, uid, gid int, report func(error), live func() []string) *retention {
+	if config.MaxBackups <= 0 && config.MaxAge <= 0 && config.MaxTotalSize <= 0 && config.Compress == CompressNone {
+		return nil
+	}
+
+	r := &retention{
+		folder:     config.Folder,
+		pattern:    backupPattern(filename),
+		compress:   config.Compress,
+		maxBackups: config.MaxBackups,
+		maxAge:     config.MaxAge,
+		maxTotal:   config.MaxTotalSize,
+		uid:        uid,
+		gid:        gid,
+		rotated:    make(chan string, 1),
+		report:     report,
+		live:       live,
+	}
+	go r.run()
+
+	return r
+}
+
+// notify schedules a cleanup after a rotation, `current` is never touched.
+func (r *retention) notify(current string) {
+	select {
+	case r.rotated <- current:
+	default:
+	}
+}
+
+// run cleans up after every rotation and, when MaxAge is set, every
+// sweep interval, until rotated is closed.
+func (r *retention) run() {
+	var sweep <-chan time.Time
+	if r.maxAge > 0 {
+		interval := r.maxAge
+		if interval > maxSweepInterval {
+			interval = maxSweepInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
+
+	var current string
+	for {
+		select {
+		case name, ok := <-r.rotated:
+			if !ok {
+				return
+			}
+			current = name
+		case <-sweep:
+			if current == "" {
+				continue
+			}
+		}
+
+		if err := r.cleanup(current); err != nil {
+			r.report(fmt.Errorf("cleaning up log files: %v", err))
+		}
+	}
+}
+
+// cleanup compresses and prunes every backup except `current`.
+func (r *retention) cleanup(current string) error {
+	backups, currentSize, err := r.backups(current)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if ext, ok := compressExt[r.compress]; ok {
+		for i, b := range backups {
+			if strings.HasSuffix(b.path, ".gz") || strings.HasSuffix(b.path, ".zst") {
+				continue
+			}
+
+			dst := b.path + ext
+			err := compressFile(b.path, dst, r.compress)
+			if os.IsNotExist(err) {
+				// compressed or removed by the retention of an evicted writer
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("compressing %s: %v", b.path, err))
+				continue
+			}
+			if err := chown(dst, r.uid, r.gid); err != nil {
+				errs = append(errs, err.Error())
+			}
+
+			if fi, err := os.Stat(dst); err == nil {
+				backups[i].path = dst
+				backups[i].size = fi.Size()
+			}
+		}
+	}
+
+	// newest first, everything after the limits is removed
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	total := currentSize
+	for i, b := range backups {
+		total += b.size
+
+		remove := r.maxBackups > 0 && i >= r.maxBackups
+		remove = remove || r.maxAge > 0 && time.Since(b.modTime) > r.maxAge
+		remove = remove || r.maxTotal > 0 && total > r.maxTotal
+		if !remove {
+			continue
+		}
+
+		total -= b.size
+		// the retention of an evicted writer may have removed it already
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backups lists the rotated files and the size of `current`.
+func (r *retention) backups(current string) ([]backup, int64, error) {
+	infos, err := ioutil.ReadDir(r.folder)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	live := map[string]bool{}
+	if r.live != nil {
+		for _, name := range r.live() {
+			live[filepath.Clean(name)] = true
+		}
+	}
+
+	var backups []backup
+	var currentSize int64
+	for _, fi := range infos {
+		if fi.IsDir() || !fi.Mode().IsRegular() || !r.pattern.MatchString(fi.Name()) {
+			continue
+		}
+
+		path := filepath.Join(r.folder, fi.Name())
+		if filepath.Clean(path) == filepath.Clean(current) {
+			currentSize = fi.Size()
+			continue
+		}
+		if live[filepath.Clean(path)] {
+			continue
+		}
+
+		backups = append(backups, backup{path: path, size: fi.Size(), modTime: fi.ModTime()})
+	}
+
+	return backups, currentSize, nil
+}
+
+// compressFile compresses `src` into `dst` and removes `src`. The
+// modification time is kept so age based pruning still works.
+func compressFile(src, dst, format string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+
+	var w io.WriteCloser
+	switch format {
+	case CompressGzip:
+		w = gzip.NewWriter(out)
+	case CompressZstd:
+		w, err = zstd.NewWriter(out)
+	}
+
+	if err == nil {
+		_, err = io.Copy(w, in)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	terr := os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		return err
+	}
+	if terr != nil {
+		return fmt.Errorf("keeping the modification time of %s: %v", dst, terr)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// newBackups creates "test.log" and the backups "test.1.log" to
+// "test.<n>.log" in a temporary folder, each `size` bytes and an hour
+// older than the one before.
+func newBackups(t *testing.T, n, size int) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	now := time.Now()
+	for i := 0; i <= n; i++ {
+		name := "test.log"
+		if i > 0 {
+			name = indexedName(name, i)
+		}
+
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func names(t *testing.T, dir string) string {
+	t.Helper()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestRetentionLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention retention
+		want      string
+	}{
+		{"max backups", retention{maxBackups: 2}, "test.1.log,test.2.log,test.log"},
+		{"max age", retention{maxAge: 150 * time.Minute}, "test.1.log,test.2.log,test.log"},
+		{"max total size", retention{maxTotal: 35}, "test.1.log,test.2.log,test.log"},
+		{"no limits", retention{}, "test.1.log,test.2.log,test.3.log,test.4.log,test.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newBackups(t, 4, 10)
+			r := tt.retention
+			r.folder = dir
+			r.pattern = backupPattern("test.log")
+
+			if err := r.cleanup(filepath.Join(dir, "test.log")); err != nil {
+				t.Fatal(err)
+			}
+			if got := names(t, dir); got != tt.want {
+				t.Errorf("kept %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetentionCompress(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		CompressGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		CompressZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for format, newReader := range readers {
+		t.Run(format, func(t *testing.T) {
+			dir := newBackups(t, 1, 100)
+			before, err := os.Stat(filepath.Join(dir, "test.1.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := retention{folder: dir, pattern: backupPattern("test.log"), compress: format}
+			if err := r.cleanup(filepath.Join(dir, "test.log")); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := filepath.Join(dir, "test.1.log"+compressExt[format])
+			if got, want := names(t, dir), "test.1.log"+compressExt[format]+",test.log"; got != want {
+				t.Fatalf("kept %s, want %s", got, want)
+			}
+
+			f, err := os.Open(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			zr, err := newReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != strings.Repeat("x", 100) {
+				t.Errorf("decompressed %q", b)
+			}
+
+			after, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !after.ModTime().Equal(before.ModTime()) {
+				t.Errorf("modification time %v, want %v", after.ModTime(), before.ModTime())
+			}
+		})
+	}
+}
+
+func TestRetentionErrorsReachOnError(t *testing.T) {
+	errs := make(chan error, 10)
+	f, path := newTestFile(t, WithMaxSize(1), WithCompress(CompressGzip), WithOnError(func(err error) { errs <- err }))
+
+	// a directory in the way of the temporary file fails the compression
+	if err := os.Mkdir(filepath.Join(filepath.Dir(path), "test.1.log.gz.tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, 0, "one")
+	write(t, f, 0, "two")
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "compressing") {
+			t.Errorf("OnError got %v, want a compression error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnError was not called")
+	}
+	if f.Stats().Errors == 0 {
+		t.Error("the error was not counted")
+	}
+}
+
+func TestRetentionSweepsWithoutRotation(t *testing.T) {
+	f, path := newTestFile(t, WithRetention(0, 100*time.Millisecond, 0))
+	write(t, f, 0, "one")
+
+	backup := filepath.Join(filepath.Dir(path), "test.1.log")
+	if err := ioutil.WriteFile(backup, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("the expired backup was not removed")
+}
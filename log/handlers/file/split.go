@@ -0,0 +1,265 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/keiwi/utils/log"
+)
+
+// fieldVerb is replaced by the SplitField value in file names.
+const fieldVerb = "%field%"
+
+// defaultMaxOpenFiles is the number of SplitField files kept open when
+// MaxOpenFiles is 0.
+const defaultMaxOpenFiles = 64
+
+// filename returns the file name pattern `format` for `e`, with the
+// SplitField value in place of %field%.
+func (c *File) filename(format string, e *log.Entry) string {
+	if c.config.SplitField == "" {
+		return format
+	}
+
+	value := "none"
+	if e != nil {
+		if v, ok := e.Fields[c.config.SplitField]; ok {
+			value = sanitize(fmt.Sprint(v))
+		}
+	}
+	return strings.Replace(format, fieldVerb, value, -1)
+}
+
+// targets returns the file name patterns `e` is written to.
+func (c *File) targets(e *log.Entry) []string {
+	names := []string{c.filename(c.config.Filename, e)}
+
+	for level, format := range c.config.LevelFiles {
+		if e.Level <= level {
+			names = append(names, c.filename(format, e))
+		}
+	}
+	return names
+}
+
+// writerFor returns the writer of the file name pattern `name`, opening
+// it on first use. Once more than MaxOpenFiles SplitField files are open
+// the least recently used one is closed, it is opened again when needed.
+func (c *File) writerFor(name string) (*writer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	c.clock++
+	if w, ok := c.writers[name]; ok {
+		w.used = c.clock
+		return w, nil
+	}
+
+	symlink := ""
+	if name == c.config.Filename {
+		symlink = c.config.Symlink
+	}
+
+	w, err := newWriter(&c.config, name, symlink, c.liveFiles)
+	if err != nil {
+		return nil, err
+	}
+	w.used = c.clock
+	c.writers[name] = w
+
+	if c.config.SplitField != "" {
+		c.evict()
+	}
+	return w, nil
+}
+
+// evict closes the least recently used writers beyond MaxOpenFiles, the
+// main writer is kept open.
+func (c *File) evict() {
+	max := c.config.MaxOpenFiles
+	if max <= 0 {
+		max = defaultMaxOpenFiles
+	}
+	if c.main != nil {
+		max++
+	}
+
+	for len(c.writers) > max {
+		var oldest string
+		for name, w := range c.writers {
+			if w == c.main {
+				continue
+			}
+			if oldest == "" || w.used < c.writers[oldest].used {
+				oldest = name
+			}
+		}
+
+		w := c.writers[oldest]
+		delete(c.writers, oldest)
+		if err := w.shutdown(); err != nil && err != ErrClosed {
+			w.report(fmt.Errorf("closing idle log file: %v", err))
+		}
+	}
+}
+
+// liveFiles returns the current file of every open writer.
+func (c *File) liveFiles() []string {
+	c.mu.Lock()
+	writers := make([]*writer, 0, len(c.writers))
+	for _, w := range c.writers {
+		writers = append(writers, w)
+	}
+	c.mu.Unlock()
+
+	names := make([]string, 0, len(writers))
+	for _, w := range writers {
+		w.Lock()
+		names = append(names, w.filename)
+		w.Unlock()
+	}
+	return names
+}
+
+// each calls `fn` for every writer and collects the errors.
+func (c *File) each(fn func(w *writer) error) error {
+	c.mu.Lock()
+	writers := make([]*writer, 0, len(c.writers))
+	for _, w := range c.writers {
+		writers = append(writers, w)
+	}
+	c.mu.Unlock()
+
+	var result error
+	for _, w := range writers {
+		if err := fn(w); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// newWriter opens a writer for the file name pattern `filename` with the
+// rotation, retention and durability settings of `config`. Retention
+// leaves the files `live` returns alone.
+func newWriter(config *Config, filename, symlink string, live func() []string) (*writer, error) {
+	uid, gid, err := lookupOwner(config.Owner, config.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &writer{
+		Mutex:      new(sync.Mutex),
+		fileFormat: filename,
+		folder:     config.Folder,
+		symlink:    symlink,
+		fileMode:   config.FileMode,
+		dirMode:    config.DirMode,
+		uid:        uid,
+		gid:        gid,
+		maxSize:    config.MaxSize,
+		maxLines:   config.MaxLines,
+
+		bufferSize:    config.BufferSize,
+		flushInterval: config.FlushInterval,
+		syncPolicy:    config.Sync,
+		syncEvery:     config.SyncEvery,
+
+		copyTruncate:  config.CopyTruncate,
+		watchInterval: config.WatchInterval,
+
+		onError: config.OnError,
+		done:    make(chan struct{}),
+	}
+
+	if w.fileMode == 0 {
+		w.fileMode = 0644
+	}
+	if w.dirMode == 0 {
+		w.dirMode = 0755
+	}
+	if w.copyTruncate && w.watchInterval <= 0 {
+		w.watchInterval = time.Second
+	}
+
+	w.retention = newRetention(config, filename, uid, gid, w.report, live)
+
+	if err := w.Init(); err != nil {
+		return nil, err
+	}
+
+	if config.ReopenOnSignal {
+		w.handleSignals(w.done)
+	}
+	if w.bufferSize > 0 && w.flushInterval > 0 {
+		w.flushEvery(w.done)
+	}
+
+	return w, nil
+}
+
+// lookupOwner resolves user and group names or IDs, -1 when empty.
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+
+	return uid, gid, nil
+}
+
+// sanitize makes a field value safe to use in a file name. Dots are
+// replaced too, the backup index follows a dot so "10.0.0.1.log" would
+// look like a backup of the value "10.0.0".
+func sanitize(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// chown changes the owner of `path` when `uid` or `gid` is set.
+func chown(path string, uid, gid int) error {
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+	return os.Chown(path, uid, gid)
+}
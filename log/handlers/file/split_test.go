@@ -0,0 +1,132 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+func writeField(t *testing.T, f *File, key string, value interface{}, msg string) {
+	t.Helper()
+
+	e := &log.Entry{
+		Level:     log.INFO,
+		Message:   msg,
+		Fields:    log.Fields{key: value},
+		Timestamp: time.Now(),
+	}
+	if err := f.Write(e, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSplitValuesArePrefixes checks that the files of a value are never
+// taken for backups of a value that is a prefix of it.
+func TestSplitValuesArePrefixes(t *testing.T) {
+	f, path := newTestFile(t,
+		WithFilename("app-%field%.log"),
+		WithSplitField("client"),
+		WithMaxOpenFiles(1),
+		WithMaxSize(1),
+		WithRetention(1, 0, 0),
+	)
+	dir := filepath.Dir(path)
+
+	clients := []string{"10.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.4"}
+	for round := 0; round < 3; round++ {
+		for _, client := range clients {
+			writeField(t, f, "client", client, fmt.Sprintf("%s/%d", client, round))
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// retention runs in the background
+	time.Sleep(50 * time.Millisecond)
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range infos {
+		for _, line := range lines(t, filepath.Join(dir, fi.Name())) {
+			client := strings.Split(line, "/")[0]
+			prefix := "app-" + sanitize(client)
+			if name := fi.Name(); name != prefix+".log" && !strings.HasPrefix(name, prefix+".") {
+				t.Errorf("%s contains %s", name, line)
+			}
+		}
+	}
+
+	for _, client := range clients {
+		got := lines(t, filepath.Join(dir, "app-"+sanitize(client)+".log"))
+		if want := client + "/2"; len(got) != 1 || got[0] != want {
+			t.Errorf("the live file of %s contains %q, want %s", client, got, want)
+		}
+	}
+}
+
+func TestSplitOpensNoneOnlyWhenUsed(t *testing.T) {
+	f, path := newTestFile(t, WithFilename("app-%field%.log"), WithSplitField("client"))
+	dir := filepath.Dir(path)
+
+	writeField(t, f, "client", "a", "one")
+	if _, err := os.Stat(filepath.Join(dir, "app-none.log")); !os.IsNotExist(err) {
+		t.Errorf("app-none.log was created before it was needed: %v", err)
+	}
+
+	write(t, f, log.INFO, "two")
+	if got := lines(t, filepath.Join(dir, "app-none.log")); len(got) != 1 || got[0] != "two" {
+		t.Errorf("app-none.log contains %q, want two", got)
+	}
+}
+
+func TestSplitMaxOpenFiles(t *testing.T) {
+	f, _ := newTestFile(t, WithFilename("app-%field%.log"), WithSplitField("client"), WithMaxOpenFiles(2))
+
+	for _, client := range []string{"a", "b", "c", "a", "d"} {
+		writeField(t, f, "client", client, client)
+	}
+
+	var open []string
+	for name := range f.writers {
+		open = append(open, filepath.Base(name))
+	}
+	sort.Strings(open)
+	if got := strings.Join(open, ","); got != "app-a.log,app-d.log" {
+		t.Errorf("open files are %s, want app-a.log,app-d.log", got)
+	}
+}
+
+// TestSplitMaxLinesAfterEviction checks that a file reopened after an
+// eviction keeps counting its lines.
+func TestSplitMaxLinesAfterEviction(t *testing.T) {
+	f, path := newTestFile(t,
+		WithFilename("app-%field%.log"),
+		WithSplitField("client"),
+		WithMaxOpenFiles(1),
+		WithMaxLines(2),
+	)
+	dir := filepath.Dir(path)
+
+	for _, msg := range []string{"a1", "b1", "a2", "b2", "a3"} {
+		writeField(t, f, "client", msg[:1], msg)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := lines(t, filepath.Join(dir, "app-a.1.log")); strings.Join(got, ",") != "a1,a2" {
+		t.Errorf("the backup of a contains %q, want a1 and a2", got)
+	}
+	if got := lines(t, filepath.Join(dir, "app-a.log")); strings.Join(got, ",") != "a3" {
+		t.Errorf("the file of a contains %q, want a3", got)
+	}
+}
@@ -1,9 +1,35 @@
 package file
 
-// stats tracks the number of output lines and bytes written.
+import "time"
+
+// Stats describes the current state of a File.
+type Stats struct {
+	// Filename is the file currently written to.
+	Filename string
+	// Bytes is the size of the current file.
+	Bytes int64
+	// Lines is the number of lines in the current file with MaxLines,
+	// and the number written since it was opened otherwise.
+	Lines int64
+	// Rotations is the number of rotations done.
+	Rotations int64
+	// Errors is the number of write and background errors.
+	Errors int64
+	// LastError is the most recent error and LastErrorTime when it
+	// happened.
+	LastError     error
+	LastErrorTime time.Time
+}
+
+// stats tracks the number of output lines and bytes written, the
+// rotations and the errors.
 type stats struct {
-	lines int64
-	bytes int64
+	lines     int64
+	bytes     int64
+	rotations int64
+	errors    int64
+	lastError error
+	lastTime  time.Time
 }
 
 // Lines returns the number of lines written.
@@ -15,3 +41,47 @@ func (s *stats) Lines() int64 {
 func (s *stats) Bytes() int64 {
 	return s.bytes
 }
+
+// failed records `err`.
+func (s *stats) failed(err error) {
+	s.errors++
+	s.lastError = err
+	s.lastTime = time.Now()
+}
+
+// Stats returns the current stats of the main file. With SplitField
+// there is no main file and the zero Stats is returned, see SplitStats.
+func (c *File) Stats() Stats {
+	if c.main == nil {
+		return Stats{}
+	}
+	return c.main.Stats()
+}
+
+// SplitStats returns the current stats of every file, the main file,
+// LevelFiles and SplitField files.
+func (c *File) SplitStats() []Stats {
+	var all []Stats
+	c.each(func(w *writer) error {
+		all = append(all, w.Stats())
+		return nil
+	})
+	return all
+}
+
+// Stats returns the current stats of the writer.
+func (w *writer) Stats() Stats {
+	w.Lock()
+	defer w.Unlock()
+
+	s := w.stats
+	return Stats{
+		Filename:      w.filename,
+		Bytes:         s.bytes,
+		Lines:         s.lines,
+		Rotations:     s.rotations,
+		Errors:        s.errors,
+		LastError:     s.lastError,
+		LastErrorTime: s.lastTime,
+	}
+}
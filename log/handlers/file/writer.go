@@ -1,58 +1,171 @@
 package file
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
+	stdlog "log"
+
 	"aahframework.org/essentials.v0"
+	"github.com/keiwi/utils/log"
 )
 
+// stderr prints errors when there is no OnError. It doesn't use the
+// standard logger, log.RedirectStdLog may send that back into the Logger
+// this handler is called under.
+var stderr = stdlog.New(os.Stderr, "", stdlog.LstdFlags)
+
 type writer struct {
 	*sync.Mutex
 	messages []string
 
 	out      io.Writer
+	file     *os.File
+	buf      *bufio.Writer
 	filename string
 
-	openDay  time.Time
 	stats    *stats
 	isClosed bool
+	stopped  bool
 
 	fileFormat string
 	folder     string
+	symlink    string
+	fileMode   os.FileMode
+	dirMode    os.FileMode
+	uid        int
+	gid        int
 	maxSize    int64
 	maxLines   int64
+
+	bufferSize    int
+	flushInterval time.Duration
+	syncPolicy    SyncPolicy
+	syncEvery     int
+	unsynced      int
+
+	copyTruncate  bool
+	watchInterval time.Duration
+	lastCheck     time.Time
+	done          chan struct{}
+
+	onError   func(error)
+	retention *retention
+	// pending holds the errors recorded while the writer was locked,
+	// they are passed on by unlock.
+	pending []error
+
+	// used orders the writers of a File by their last use, guarded by
+	// the File.
+	used uint64
 }
 
 func (w *writer) Init() error {
+	w.stats = &stats{}
 	w.filename = w.getFile()
 
-	return w.openFile()
+	if err := w.openFile(); err != nil {
+		return err
+	}
+
+	if w.retention != nil {
+		w.retention.notify(w.filename)
+	}
+	return nil
 }
 
-func (w *writer) Write(message []byte) {
+func (w *writer) Write(message []byte, level log.Level) error {
 	w.Lock()
-	defer w.Unlock()
+	err := w.write(message, level)
+	if err == ErrClosed {
+		// closed by Close or as an idle SplitField file, not a failure
+		w.unlock()
+		return err
+	}
+	if err != nil {
+		w.stats.failed(err)
+	}
+	w.unlock()
+
+	if err != nil && w.onError != nil {
+		w.onError(err)
+	}
+	return err
+}
+
+// report records an error of background work and passes it on.
+func (w *writer) report(err error) {
+	w.Lock()
+	w.stats.failed(err)
+	w.Unlock()
+
+	w.notify(err)
+}
+
+// failed records an error while the writer is locked, it is passed on
+// once the writer is unlocked with unlock.
+func (w *writer) failed(err error) {
+	w.stats.failed(err)
+	w.pending = append(w.pending, err)
+}
+
+// unlock unlocks the writer and passes on the errors recorded by failed.
+func (w *writer) unlock() {
+	pending := w.pending
+	w.pending = nil
+	w.Unlock()
+
+	for _, err := range pending {
+		w.notify(err)
+	}
+}
+
+// notify passes `err` to OnError, or prints it when there is none.
+func (w *writer) notify(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	} else {
+		stderr.Printf("error in file logger: %s", err)
+	}
+}
 
-	if w.isRotate() {
-		_ = w.rotateFile()
+func (w *writer) write(message []byte, level log.Level) error {
+	if w.stopped {
+		return ErrClosed
+	}
 
-		// reset rotation values
-		w.openDay = time.Now()
-		w.stats.lines = 0
-		w.stats.bytes = 0
+	if err := w.watch(); err != nil {
+		return fmt.Errorf("reopening log file: %v", err)
 	}
 
-	size, _ := w.out.Write(message)
+	if !w.copyTruncate && w.isRotate() {
+		if err := w.rotateFile(); err != nil {
+			return fmt.Errorf("rotating log file: %v", err)
+		}
+		w.stats.rotations++
+
+		if w.retention != nil {
+			w.retention.notify(w.filename)
+		}
+	}
+
+	size, err := w.out.Write(message)
 
 	// calculate receiver stats
 	w.stats.bytes += int64(size)
 	w.stats.lines += int64(bytes.Count(message, []byte("\n")))
+
+	if err != nil {
+		return err
+	}
+	return w.commit(level)
 }
 
 func (w *writer) open(file string, flag int, perm os.FileMode) (*os.File, error) {
@@ -68,71 +181,157 @@ func (w *writer) isRotate() bool {
 		return true
 	}
 
-	if !w.openDay.IsZero() && !w.sameDay() {
-		return true
-	}
-
-	return false
+	// a new hour, day or week gives a new name
+	return w.getFile() != w.filename
 }
 
+// rotateFile closes the live file and opens the next one. Within the same
+// period the live file is first renamed to the next free backup index,
+// app-2026-10-18.log becomes app-2026-10-18.1.log.
 func (w *writer) rotateFile() error {
-	if _, err := os.Lstat(filepath.Join(w.folder, w.filename)); err == nil {
-		w.close()
-		if err = os.Rename(filepath.Join(w.folder, w.filename), w.getFile()); err != nil {
-			return err
-		}
+	next := w.getFile()
+	w.close()
+
+	var err error
+	if next == w.filename {
+		err = w.renameBackup()
 	}
 
-	w.filename = w.getFile()
+	w.filename = next
+	if oerr := w.openFile(); oerr != nil {
+		return oerr
+	}
+	return err
+}
 
-	if err := w.openFile(); err != nil {
+// renameBackup renames the live file to its next free backup index.
+func (w *writer) renameBackup() error {
+	dir, name := filepath.Split(w.filename)
+
+	infos, err := ioutil.ReadDir(filepath.Clean(dir))
+	if err != nil {
 		return err
 	}
+	names := make([]string, len(infos))
+	for i, fi := range infos {
+		names[i] = fi.Name()
+	}
 
+	backup := filepath.Join(dir, indexedName(name, nextIndex(name, names)))
+	if err := os.Rename(w.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
 func (w *writer) openFile() error {
-	err := ess.MkDirAll(w.folder, 0755)
+	err := ess.MkDirAll(filepath.Dir(w.filename), w.dirMode)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0755)
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, w.fileMode)
 	if err != nil {
 		return err
 	}
 
+	if w.uid >= 0 || w.gid >= 0 {
+		if err := file.Chown(w.uid, w.gid); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
 	fileStat, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return err
 	}
 
+	w.file = file
 	w.out = file
+	if w.bufferSize > 0 {
+		if w.buf == nil {
+			w.buf = bufio.NewWriterSize(file, w.bufferSize)
+		} else {
+			w.buf.Reset(file)
+		}
+		w.out = w.buf
+	}
+
+	// the lines of an existing file are only needed for MaxLines,
+	// reading a large file is too slow to do it otherwise
+	var lines int64
+	if w.maxLines > 0 && fileStat.Size() > 0 {
+		if lines, err = countLines(w.filename); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
 	w.isClosed = false
-	w.stats = &stats{}
+	w.stats.lines = lines
 	w.stats.bytes = fileStat.Size()
-	w.stats.lines = int64(ess.LineCntr(file))
 
-	return nil
+	return w.link()
+}
+
+// countLines returns the number of lines in the file `filename`.
+func countLines(filename string) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte("\n")))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
 }
 
-func (w *writer) sameDay() bool {
-	y1, m1, d1 := w.openDay.Date()
-	y2, m2, d2 := time.Now().Date()
-	return y1 == y2 && m1 == m2 && d1 == d2
+// link points the symlink at the live file, replacing it atomically.
+func (w *writer) link() error {
+	if w.symlink == "" {
+		return nil
+	}
+
+	link := filepath.Join(w.folder, w.symlink)
+	target, err := filepath.Rel(filepath.Dir(link), w.filename)
+	if err != nil {
+		return err
+	}
+
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, link)
 }
 
+// close flushes and closes the live file. Entries lost because the
+// buffer can't be flushed are recorded with failed.
 func (w *writer) close() {
 	if !w.isClosed {
-		ess.CloseQuietly(w.out)
-		w.isClosed = false
+		if w.buf != nil {
+			if err := w.buf.Flush(); err != nil {
+				w.failed(fmt.Errorf("flushing log file %s: %v", w.filename, err))
+			}
+		}
+		ess.CloseQuietly(w.file)
+		w.isClosed = true
 	}
 }
 
 func (w *writer) getFile() string {
-	file := w.fileFormat
-	date := time.Now().Format("2006-01-02")
-	file = strings.Replace(file, "%date%", date, -1)
-	return filepath.Join(w.folder, file)
+	return filepath.Join(w.folder, expandPattern(w.fileFormat, time.Now()))
 }
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestStats(t *testing.T) {
+	f, path := newTestFile(t, WithMaxLines(2))
+
+	for _, msg := range []string{"one", "two", "three"} {
+		write(t, f, log.INFO, msg)
+	}
+
+	s := f.Stats()
+	if s.Filename != path || s.Bytes != int64(len("three\n")) || s.Lines != 1 || s.Rotations != 1 || s.Errors != 0 {
+		t.Errorf("Stats = %+v, want one rotation and three in %s", s, path)
+	}
+	if got := lines(t, filepath.Join(filepath.Dir(path), "test.1.log")); strings.Join(got, ",") != "one,two" {
+		t.Errorf("the backup contains %q, want one and two", got)
+	}
+}
+
+func TestOnErrorLostBuffer(t *testing.T) {
+	var errs []error
+	f, _ := newTestFile(t,
+		WithMaxLines(1),
+		WithBuffer(1024, 0),
+		WithOnError(func(err error) { errs = append(errs, err) }),
+	)
+
+	write(t, f, log.INFO, "one")
+	// the buffered line can't be flushed when the file rotates
+	f.main.file.Close()
+	write(t, f, log.INFO, "two")
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "flushing log file") {
+		t.Errorf("OnError got %v, want the flush error", errs)
+	}
+	if s := f.Stats(); s.Errors != 1 || s.LastError == nil || s.LastErrorTime.IsZero() {
+		t.Errorf("Stats = %+v, want the flush error counted", s)
+	}
+}
+
+func TestOnErrorWrite(t *testing.T) {
+	var errs []error
+	f, _ := newTestFile(t, WithOnError(func(err error) { errs = append(errs, err) }))
+
+	f.main.file.Close()
+	e := &log.Entry{Level: log.INFO, Message: "lost"}
+	if err := f.Write(e, 0); err == nil {
+		t.Fatal("writing to a closed file succeeded")
+	}
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), os.ErrClosed.Error()) {
+		t.Errorf("OnError got %v, want the write error", errs)
+	}
+	if s := f.Stats(); s.Errors != 1 {
+		t.Errorf("Stats.Errors = %d, want 1", s.Errors)
+	}
+}
+
+func TestMaxLinesCountsExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+	if err := ioutil.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := New(WithFolder(dir), WithFilename("test.log"), WithFormat("{{ .Message }}"), WithMaxLines(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if s := f.Stats(); s.Lines != 2 {
+		t.Errorf("Lines = %d after opening, want 2", s.Lines)
+	}
+
+	write(t, f, log.INFO, "three")
+	write(t, f, log.INFO, "four")
+
+	if s := f.Stats(); s.Lines != 1 || s.Rotations != 1 {
+		t.Errorf("Stats = %+v, want a rotation at three lines", s)
+	}
+	if got := lines(t, filepath.Join(dir, "test.1.log")); strings.Join(got, ",") != "one,two,three" {
+		t.Errorf("the backup contains %q, want one, two and three", got)
+	}
+}
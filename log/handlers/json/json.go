@@ -0,0 +1,105 @@
+// Package json implements a reporter writing entries as JSON lines, one
+// object per entry, for log shippers and other machines.
+package json
+
+import (
+	j "encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// entry is the JSON form of a log entry.
+type entry struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Level     log.Level  `json:"level"`
+	Message   string     `json:"message"`
+	Fields    log.Fields `json:"fields,omitempty"`
+}
+
+// JSON is a reporter writing entries as JSON lines to Writer.
+type JSON struct {
+	Writer io.Writer
+
+	// Formatter renders the message field when set, the message of the
+	// entry is used as is when nil.
+	Formatter log.Formatter
+
+	mu  sync.Mutex
+	out io.Writer
+	enc *j.Encoder
+}
+
+// New creates a JSON reporter writing to `w`.
+func New(w io.Writer) *JSON {
+	return &JSON{Writer: w}
+}
+
+// Config configures a JSON reporter created from options.
+type Config struct {
+	// Output is "stdout" or "stderr", stdout when empty.
+	Output string
+	// Format is the template of the message field, the message of the
+	// entry when empty.
+	Format string
+}
+
+func init() {
+	log.RegisterReporter("json", func(o log.Options) (log.Reporter, error) {
+		var config Config
+		if err := o.Decode(&config); err != nil {
+			return nil, fmt.Errorf("json logger: %v", err)
+		}
+
+		var h *JSON
+		switch config.Output {
+		case "", "stdout":
+			h = New(os.Stdout)
+		case "stderr":
+			h = New(os.Stderr)
+		default:
+			return nil, fmt.Errorf("json logger: unknown output %q", config.Output)
+		}
+
+		if config.Format != "" {
+			formatter, err := log.NewStringFormatter(config.Format)
+			if err != nil {
+				return nil, fmt.Errorf("json logger: %v", err)
+			}
+			h.Formatter = formatter
+		}
+		return h, nil
+	})
+}
+
+func (h *JSON) Write(e *log.Entry, calldepth int) error {
+	msg := e.Message
+	if h.Formatter != nil {
+		e.Formatted = h.Formatter.Format(e, calldepth+1)
+
+		var err error
+		if msg, err = h.Formatter.Finalize(e.Formatted); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Writer may change between writes
+	if h.enc == nil || h.out != h.Writer {
+		h.out = h.Writer
+		h.enc = j.NewEncoder(h.Writer)
+	}
+
+	return h.enc.Encode(entry{
+		Timestamp: e.Timestamp,
+		Level:     e.Level,
+		Message:   msg,
+		Fields:    e.Fields,
+	})
+}
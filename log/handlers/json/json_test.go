@@ -0,0 +1,104 @@
+package json
+
+import (
+	"bytes"
+	j "encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestWriteStackAndCauses(t *testing.T) {
+	var b bytes.Buffer
+	h := New(&b)
+
+	h.Write(&log.Entry{
+		Timestamp: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Level:     log.ERROR,
+		Message:   "failed",
+		Fields: log.Fields{
+			"causes": []string{"middle", "inner"},
+			"callstack": log.Stack{
+				{Function: "main.run", File: "/src/main.go", Line: 12},
+			},
+		},
+	}, 0)
+
+	var got struct {
+		Message string
+		Fields  struct {
+			Causes    []string
+			Callstack []log.Frame
+		}
+	}
+	if err := j.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %s: %v", b.String(), err)
+	}
+	if got.Message != "failed" || len(got.Fields.Causes) != 2 {
+		t.Errorf("decoded %+v", got)
+	}
+	if s := got.Fields.Callstack; len(s) != 1 || s[0].Function != "main.run" || s[0].Line != 12 {
+		t.Errorf("callstack = %+v, want the frame of main.run", s)
+	}
+}
+
+func TestWriterChange(t *testing.T) {
+	var first, second bytes.Buffer
+	h := New(&first)
+
+	h.Write(&log.Entry{Level: log.INFO, Message: "first"}, 0)
+	h.Writer = &second
+	h.Write(&log.Entry{Level: log.INFO, Message: "second"}, 0)
+
+	if !bytes.Contains(first.Bytes(), []byte(`"first"`)) || bytes.Contains(first.Bytes(), []byte(`"second"`)) {
+		t.Errorf("first writer got %s", first.String())
+	}
+	if !bytes.Contains(second.Bytes(), []byte(`"second"`)) {
+		t.Errorf("second writer got %s", second.String())
+	}
+}
+
+func TestConfigFormat(t *testing.T) {
+	f, err := ioutil.TempFile("", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	stdout := os.Stdout
+	os.Stdout = f
+	l, err := log.FromConfig(&log.Config{
+		Reporters: []log.ReporterConfig{{Type: "json", Format: "> {{ .Message }}"}},
+	})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("formatted")
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got entry
+	if err := j.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	if got.Message != "> formatted" {
+		t.Errorf("message = %q, want %q", got.Message, "> formatted")
+	}
+}
+
+func TestConfigUnknownOption(t *testing.T) {
+	_, err := log.FromConfig(&log.Config{
+		Reporters: []log.ReporterConfig{{Type: "json", Options: log.Options{"colour": "never"}}},
+	})
+	if err == nil {
+		t.Error("unknown option accepted")
+	}
+}
@@ -0,0 +1,153 @@
+package ring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// ParseQuery reads a query from URL parameters:
+//
+//	level=warn              warn and more severe entries
+//	since=15m, until=...    a duration ago or an RFC 3339 time
+//	after=1042              entries after the sequence number
+//	field=client_id=42      a field value, may be repeated
+//	q=timeout               text in the message or a field value
+//	limit=100               only the newest entries
+func ParseQuery(values url.Values) (Query, error) {
+	var q Query
+	now := time.Now()
+
+	if v := values.Get("level"); v != "" {
+		level, err := log.ParseLevel(v)
+		if err != nil {
+			return q, err
+		}
+		for l := log.FATAL; l <= level; l++ {
+			q.Levels = append(q.Levels, l)
+		}
+	}
+
+	var err error
+	if q.Since, err = parseTime(values.Get("since"), now); err != nil {
+		return q, fmt.Errorf("since: %v", err)
+	}
+	if q.Until, err = parseTime(values.Get("until"), now); err != nil {
+		return q, fmt.Errorf("until: %v", err)
+	}
+
+	if v := values.Get("after"); v != "" {
+		if q.After, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return q, fmt.Errorf("after: %v", err)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("limit: %v", err)
+		}
+	}
+
+	for _, v := range values["field"] {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return q, fmt.Errorf("field: expected key=value, got %q", v)
+		}
+		if q.Fields == nil {
+			q.Fields = map[string]string{}
+		}
+		q.Fields[kv[0]] = kv[1]
+	}
+
+	q.Text = values.Get("q")
+	return q, nil
+}
+
+// parseTime reads a duration before `now` or an RFC 3339 time, the zero
+// time when `s` is empty.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Handler returns a http.Handler serving the entries of `r` matching the
+// query parameters, see ParseQuery, as a JSON array. With follow=1 the
+// matches are streamed as JSON lines instead, followed by new entries as
+// they are written until the client goes away.
+//
+//	curl 'http://agent:8080/debug/log?level=warn&since=10m'
+//	curl 'http://agent:8080/debug/log?field=client_id=42&follow=1'
+func Handler(r *Ring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		q, err := ParseQuery(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if follow, _ := strconv.ParseBool(req.FormValue("follow")); follow {
+			tail(w, req, r, q)
+			return
+		}
+
+		records := r.Query(q)
+		if records == nil {
+			records = []Record{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	})
+}
+
+// tail streams the records matching `q` until the client goes away or
+// the ring is closed.
+func tail(w http.ResponseWriter, req *http.Request, r *Ring, q Query) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	records, ch, cancel := r.Subscribe(q)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case rec, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(rec); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package ring implements a reporter keeping the most recent entries in
+// memory, to be queried and tailed over HTTP or NATS while debugging a
+// running process.
+//
+// The ring sees the entries the logger writes, so to keep DEBUG entries
+// in it while the other reporters show less, set the logger to DEBUG and
+// give the other reporters their own level:
+//
+//	r := ring.New(10000)
+//	log.Log.SetLevel(log.DEBUG)
+//	log.Log.Reporters = []log.Reporter{log.Filtered(cli.NewCli(), log.MinLevel(log.INFO)), r}
+//	http.Handle("/debug/log", ring.Handler(r))
+package ring
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keiwi/utils/log"
+)
+
+// tailBuffer is how many records a subscriber may fall behind before
+// records are dropped for it.
+const tailBuffer = 256
+
+// Record is an entry kept by a Ring.
+type Record struct {
+	// Seq numbers the entries written to the ring, starting at 1.
+	Seq       uint64     `json:"seq"`
+	Timestamp time.Time  `json:"timestamp"`
+	Level     log.Level  `json:"level"`
+	Message   string     `json:"message"`
+	Fields    log.Fields `json:"fields,omitempty"`
+}
+
+// Ring is a reporter keeping the last entries written to it. It is safe
+// for concurrent use.
+type Ring struct {
+	mu      sync.RWMutex
+	records []Record
+	next    int
+	full    bool
+	seq     uint64
+	subs    map[*subscription]struct{}
+}
+
+// subscription is a live tail of a ring.
+type subscription struct {
+	query Query
+	ch    chan Record
+}
+
+// New creates a ring keeping the last `size` entries.
+func New(size int) *Ring {
+	if size < 1 {
+		size = 1
+	}
+	return &Ring{
+		records: make([]Record, size),
+		subs:    map[*subscription]struct{}{},
+	}
+}
+
+var (
+	ringsMu sync.Mutex
+	rings   = map[string]*Ring{}
+)
+
+// Get returns the ring created from a config with the "name" option,
+// nil when there is none.
+func Get(name string) *Ring {
+	ringsMu.Lock()
+	defer ringsMu.Unlock()
+
+	return rings[name]
+}
+
+// Config configures a ring created from options.
+type Config struct {
+	// Size is the number of entries kept, 1000 when 0.
+	Size int
+	// Name makes the ring available to Get, "default" when empty.
+	Name string
+}
+
+func init() {
+	log.RegisterReporter("ring", func(o log.Options) (log.Reporter, error) {
+		var config Config
+		if err := o.Decode(&config); err != nil {
+			return nil, fmt.Errorf("ring logger: %v", err)
+		}
+		if config.Size == 0 {
+			config.Size = 1000
+		}
+		if config.Name == "" {
+			config.Name = "default"
+		}
+
+		r := New(config.Size)
+		ringsMu.Lock()
+		rings[config.Name] = r
+		ringsMu.Unlock()
+		return r, nil
+	})
+}
+
+func (r *Ring) Write(e *log.Entry, calldepth int) error {
+	fields := make(log.Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		fields[k] = snapshot(v)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	rec := Record{
+		Seq:       r.seq,
+		Timestamp: e.Timestamp,
+		Level:     e.Level,
+		Message:   e.Message,
+		Fields:    fields,
+	}
+
+	r.records[r.next] = rec
+	r.next++
+	if r.next == len(r.records) {
+		r.next, r.full = 0, true
+	}
+
+	for s := range r.subs {
+		if !s.query.Match(rec) {
+			continue
+		}
+		select {
+		case s.ch <- rec:
+		default:
+			// the subscriber fell behind, it must not block logging
+		}
+	}
+	return nil
+}
+
+// snapshot returns a copy of the field value `v` that stays the same when
+// the caller changes `v` later and always encodes to JSON. Basic values,
+// call stacks and error causes are kept, anything else is stored as its
+// fmt.Sprint text.
+func snapshot(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, string, time.Time, time.Duration, log.Level,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v)
+		}
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+		return v
+	case log.Stack:
+		return append(log.Stack(nil), v...)
+	case []string:
+		return append([]string(nil), v...)
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// Records returns the kept entries, oldest first.
+func (r *Ring) Records() []Record {
+	return r.Query(Query{})
+}
+
+// Query returns the kept entries matching `q`, oldest first.
+func (r *Ring) Query(q Query) []Record {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.query(q)
+}
+
+func (r *Ring) query(q Query) []Record {
+	var records []Record
+	if r.full {
+		records = q.appendMatches(records, r.records[r.next:])
+	}
+	records = q.appendMatches(records, r.records[:r.next])
+
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records
+}
+
+// Subscribe returns the kept entries matching `q`, as Query, and a
+// channel receiving the ones written afterwards. A subscriber falling
+// far behind misses entries rather than slowing down logging. Call
+// cancel to stop receiving, the channel is closed then or when the ring
+// is closed.
+func (r *Ring) Subscribe(q Query) (records []Record, ch <-chan Record, cancel func()) {
+	s := &subscription{query: q, ch: make(chan Record, tailBuffer)}
+	// a live tail has no end, only the backlog is limited
+	s.query.Limit = 0
+
+	r.mu.Lock()
+	records = r.query(q)
+	if r.subs == nil {
+		close(s.ch)
+	} else {
+		r.subs[s] = struct{}{}
+	}
+	r.mu.Unlock()
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			r.mu.Lock()
+			if _, ok := r.subs[s]; ok {
+				delete(r.subs, s)
+				close(s.ch)
+			}
+			r.mu.Unlock()
+		})
+	}
+	return records, s.ch, cancel
+}
+
+// Close ends the subscriptions, entries are still kept.
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	for s := range r.subs {
+		close(s.ch)
+	}
+	r.subs = nil
+	r.mu.Unlock()
+	return nil
+}
+
+// Query selects records, the zero Query selects all of them.
+type Query struct {
+	// Levels are the levels to return, all when empty.
+	Levels []log.Level `json:"levels,omitempty"`
+	// Since and Until limit the timestamps, unbounded when zero.
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+	// After only returns records with a higher Seq, to poll for new ones.
+	After uint64 `json:"after,omitempty"`
+	// Fields must all be set, compared with their fmt.Sprint form.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Text must be in the message or a field value, ignoring case.
+	Text string `json:"text,omitempty"`
+	// Limit returns only the newest records, all when 0.
+	Limit int `json:"limit,omitempty"`
+}
+
+// Match reports whether `rec` is selected by the query, ignoring Limit.
+func (q Query) Match(rec Record) bool {
+	if rec.Seq <= q.After {
+		return false
+	}
+	if len(q.Levels) > 0 && !hasLevel(q.Levels, rec.Level) {
+		return false
+	}
+	if !q.Since.IsZero() && rec.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && rec.Timestamp.After(q.Until) {
+		return false
+	}
+
+	for k, v := range q.Fields {
+		value, ok := rec.Fields[k]
+		if !ok || fmt.Sprint(value) != v {
+			return false
+		}
+	}
+
+	if q.Text != "" {
+		return containsText(rec, strings.ToLower(q.Text))
+	}
+	return true
+}
+
+func (q Query) appendMatches(records, from []Record) []Record {
+	for _, rec := range from {
+		if q.Match(rec) {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+func hasLevel(levels []log.Level, level log.Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// containsText reports whether the lower case `text` is in the message
+// or a field value of `rec`.
+func containsText(rec Record, text string) bool {
+	if strings.Contains(strings.ToLower(rec.Message), text) {
+		return true
+	}
+	for _, v := range rec.Fields {
+		if strings.Contains(strings.ToLower(fmt.Sprint(v)), text) {
+			return true
+		}
+	}
+	return false
+}
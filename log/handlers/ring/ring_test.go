@@ -0,0 +1,70 @@
+package ring
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestWriteSnapshotsFields(t *testing.T) {
+	r := New(1)
+	ids := []int{1, 2}
+	r.Write(&log.Entry{
+		Level:   log.INFO,
+		Message: "hello",
+		Fields: log.Fields{
+			"ids":   ids,
+			"count": 3,
+			"err":   errors.New("failed"),
+			"fn":    func() {},
+			"ch":    make(chan int),
+			"nan":   math.NaN(),
+		},
+	}, 1)
+	ids[0] = 42
+
+	rec := r.Records()[0]
+	if rec.Fields["ids"] != "[1 2]" || rec.Fields["count"] != 3 || rec.Fields["err"] != "failed" || rec.Fields["nan"] != "NaN" {
+		t.Errorf("Fields = %v", rec.Fields)
+	}
+	if _, err := json.Marshal(r.Records()); err != nil {
+		t.Errorf("encoding the records: %v", err)
+	}
+}
+
+func TestWriteKeepsStackAndCauses(t *testing.T) {
+	r := New(1)
+	stack := log.Stack{{Function: "main.run", File: "/src/main.go", Line: 12}}
+	causes := []string{"middle", "inner"}
+	r.Write(&log.Entry{
+		Level:   log.ERROR,
+		Message: "failed",
+		Fields:  log.Fields{"callstack": stack, "causes": causes},
+	}, 1)
+	stack[0].Line = 42
+	causes[0] = "changed"
+
+	data, err := json.Marshal(r.Records()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Fields struct {
+			Causes    []string
+			Callstack []log.Frame
+		}
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	if len(got.Fields.Causes) != 2 || got.Fields.Causes[0] != "middle" {
+		t.Errorf("causes = %q, want middle and inner", got.Fields.Causes)
+	}
+	if len(got.Fields.Callstack) != 1 || got.Fields.Callstack[0].Line != 12 {
+		t.Errorf("callstack = %+v, want main.run at line 12", got.Fields.Callstack)
+	}
+}
@@ -0,0 +1,149 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+// Package syslog implements a reporter writing entries to the local or a
+// remote syslog daemon.
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	"github.com/keiwi/utils/log"
+)
+
+// SyslogFormatter is the default formatter, syslog adds the time and tag.
+var SyslogFormatter = log.MustStringFormatter(`{{ .Message }}{{ .ParsedFields }}`)
+
+// facilities are the names of the syslog facilities.
+var facilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// Config configures a Syslog reporter.
+type Config struct {
+	// Network and Address of the daemon, "udp" and "logs:514", the local
+	// daemon when empty.
+	Network string
+	Address string
+	// Tag is prepended to every message, the program name when empty.
+	Tag string
+	// Facility such as "daemon" or "local0", "user" when empty.
+	Facility string
+	// Format is the template of an entry, SyslogFormatter when empty.
+	Format string
+}
+
+// Syslog is a reporter writing entries to syslog with the severity of
+// their level.
+type Syslog struct {
+	Formatter log.Formatter
+
+	w *syslog.Writer
+}
+
+// New connects to the syslog daemon of `config`.
+func New(config *Config) (*Syslog, error) {
+	facility := syslog.LOG_USER
+	if config.Facility != "" {
+		f, ok := facilities[strings.ToLower(config.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("syslog logger: unknown facility %q", config.Facility)
+		}
+		facility = f
+	}
+
+	s := &Syslog{Formatter: SyslogFormatter}
+	if config.Format != "" {
+		formatter, err := log.NewStringFormatter(config.Format)
+		if err != nil {
+			return nil, err
+		}
+		s.Formatter = formatter
+	}
+
+	w, err := syslog.Dial(config.Network, config.Address, facility|syslog.LOG_INFO, config.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("syslog logger: %v", err)
+	}
+	s.w = w
+
+	return s, nil
+}
+
+func init() {
+	log.RegisterReporter("syslog", func(o log.Options) (log.Reporter, error) {
+		var config Config
+		if err := o.Decode(&config); err != nil {
+			return nil, fmt.Errorf("syslog logger: %v", err)
+		}
+
+		s, err := New(&config)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
+func (s *Syslog) Write(e *log.Entry, calldepth int) error {
+	data := s.Formatter.Format(e, calldepth+1)
+	data["ParsedFields"] = parseEntry(e)
+
+	e.Formatted = data
+
+	msg, err := s.Formatter.Finalize(e.Formatted)
+	if err != nil {
+		return err
+	}
+
+	switch e.Level {
+	case log.FATAL:
+		return s.w.Crit(msg)
+	case log.ERROR:
+		return s.w.Err(msg)
+	case log.WARN:
+		return s.w.Warning(msg)
+	case log.INFO:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
+
+// Close closes the connection to the daemon.
+func (s *Syslog) Close() error {
+	return s.w.Close()
+}
+
+// parseEntry formats the fields as " key=value", sorted by name.
+func parseEntry(e *log.Entry) string {
+	var b bytes.Buffer
+	for _, name := range e.Fields.Names() {
+		// syslog messages are a single line
+		value := strings.Replace(fmt.Sprint(e.Fields[name]), "\n", " ", -1)
+		fmt.Fprintf(&b, " %s=%s", name, value)
+	}
+	return b.String()
+}
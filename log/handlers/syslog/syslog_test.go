@@ -0,0 +1,27 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestParseEntryIsOneLine(t *testing.T) {
+	e := &log.Entry{
+		Fields: log.Fields{
+			"causes": []string{"middle", "inner"},
+			"callstack": log.Stack{
+				{Function: "main.run", File: "/src/main.go", Line: 12},
+				{Function: "main.main", File: "/src/main.go", Line: 5},
+			},
+		},
+	}
+
+	want := " callstack=main.run \t/src/main.go:12 main.main \t/src/main.go:5 causes=[middle inner]"
+	if got := parseEntry(e); got != want {
+		t.Errorf("parseEntry = %q, want %q", got, want)
+	}
+}
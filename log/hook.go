@@ -0,0 +1,126 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// ErrDrop can be returned by a hook to drop the entry, the remaining
+// hooks and the reporters will not see it.
+var ErrDrop = errors.New("log: entry dropped")
+
+// Hook is run for every finalized entry before the reporters see it.
+//
+// Hooks may change the entry in place. Entry.Formatted holds the data of
+// the logger's Formatter. Hooks run while the logger is locked, so they
+// must not log through the same logger.
+type Hook interface {
+	Fire(e *Entry) error
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as hooks.
+type HookFunc func(e *Entry) error
+
+// Fire calls f(e).
+func (f HookFunc) Fire(e *Entry) error {
+	return f(e)
+}
+
+// AddHook adds a hook to the logger, hooks run in the order they were added.
+func (l *Logger) AddHook(h Hook) {
+	root := l.core()
+	root.Lock()
+	root.hooks = append(root.hooks, h)
+	root.Unlock()
+}
+
+// fire runs the hooks on `e` and reports whether it should be written.
+// A failing or panicking hook is reported and skipped.
+func (l *Logger) fire(e *Entry) bool {
+	for _, h := range l.hooks {
+		err := fireHook(h, e)
+		if err == ErrDrop {
+			return false
+		}
+		if err != nil {
+			l.metrics.hookFailed()
+			stderr.Printf("error in log hook: %s", err)
+		}
+	}
+	return true
+}
+
+// fireHook runs `h`, turning a panic into an error.
+func fireHook(h Hook, e *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panic: %v", r)
+		}
+	}()
+	return h.Fire(e)
+}
+
+// FilteredHook returns a hook that only runs `h` for entries accepted by
+// all `filters`, e.g. FilteredHook(alert, MinLevel(ERROR)).
+func FilteredHook(h Hook, filters ...Filter) Hook {
+	return HookFunc(func(e *Entry) error {
+		if !accepts(filters, e) {
+			return nil
+		}
+		return h.Fire(e)
+	})
+}
+
+// FieldsHook returns a hook setting `fields` on every entry, fields
+// already set on the entry are kept.
+func FieldsHook(fields Fielder) Hook {
+	f := fields.Fields()
+	return HookFunc(func(e *Entry) error {
+		for k, v := range f {
+			if _, ok := e.Fields[k]; !ok {
+				e.Fields[k] = v
+			}
+		}
+		return nil
+	})
+}
+
+// HostnameHook returns a hook setting the "hostname" field.
+func HostnameHook() Hook {
+	return FieldsHook(Fields{"hostname": hostname})
+}
+
+// PIDHook returns a hook setting the "pid" field.
+func PIDHook() Hook {
+	return FieldsHook(Fields{"pid": pid})
+}
+
+// VersionHook returns a hook setting the "version" field.
+func VersionHook(version string) Hook {
+	return FieldsHook(Fields{"version": version})
+}
+
+// GoroutineHook returns a hook setting the "goroutine" field to the ID
+// of the goroutine that logged the entry.
+func GoroutineHook() Hook {
+	return HookFunc(func(e *Entry) error {
+		e.Fields["goroutine"] = goroutineID()
+		return nil
+	})
+}
+
+// goroutineID returns the ID of the current goroutine, parsed from the
+// "goroutine 18 [running]:" header of its stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,111 @@
+package log_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+// appendHook appends `name` to the "hooks" field.
+func appendHook(name string) log.Hook {
+	return log.HookFunc(func(e *log.Entry) error {
+		prev, _ := e.Fields["hooks"].(string)
+		e.Fields["hooks"] = prev + name
+		return nil
+	})
+}
+
+func TestHooksRunInOrder(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	l.AddHook(appendHook("a"))
+	l.Named("child").AddHook(appendHook("b"))
+	l.AddHook(appendHook("c"))
+
+	l.Info("ordered")
+	if !rec.FieldEquals("hooks", "abc") {
+		t.Errorf("hooks field = %v, want abc", rec.Last().Fields["hooks"])
+	}
+}
+
+func TestHookErrDrop(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	later := false
+	l.AddHook(log.HookFunc(func(e *log.Entry) error {
+		if e.Message == "secret" {
+			return log.ErrDrop
+		}
+		return nil
+	}))
+	l.AddHook(log.HookFunc(func(e *log.Entry) error {
+		later = later || e.Message == "secret"
+		return nil
+	}))
+
+	l.Info("secret")
+	l.Info("public")
+
+	if rec.Len() != 1 || !rec.HasMessage("public") {
+		t.Errorf("recorded %v, want only public", rec.Entries())
+	}
+	if later {
+		t.Error("a hook after ErrDrop saw the dropped entry")
+	}
+}
+
+func TestHookFailuresAreIsolated(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	l.AddHook(log.HookFunc(func(e *log.Entry) error { panic("boom") }))
+	l.AddHook(log.HookFunc(func(e *log.Entry) error { return errors.New("failed") }))
+	l.AddHook(appendHook("ok"))
+
+	l.Info("first")
+	// the logger is still usable after a hook panicked while it was locked
+	l.Info("second")
+
+	if rec.Len() != 2 || !rec.FieldEquals("hooks", "ok") {
+		t.Errorf("recorded %v, want both entries through the last hook", rec.Entries())
+	}
+	if n := l.Metrics().Snapshot().HookErrors; n != 4 {
+		t.Errorf("HookErrors = %d, want 4", n)
+	}
+}
+
+func TestFilteredHook(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	l.AddHook(log.FilteredHook(appendHook("alert"), log.MinLevel(log.ERROR)))
+
+	l.Error("bad")
+	l.Info("fine")
+
+	for _, e := range rec.Entries() {
+		_, ok := e.Fields["hooks"]
+		if ok != (e.Level == log.ERROR) {
+			t.Errorf("entry %q has hooks field %v", e.Message, ok)
+		}
+	}
+}
+
+func TestFieldsHooks(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	l.AddHook(log.VersionHook("1.2.3"))
+	l.AddHook(log.PIDHook())
+	l.AddHook(log.HostnameHook())
+	l.AddHook(log.GoroutineHook())
+
+	l.WithField("version", "override").Info("entry")
+
+	e := rec.Last()
+	if e.Fields["version"] != "override" {
+		t.Errorf("version = %v, want the entry field kept", e.Fields["version"])
+	}
+	for _, k := range []string{"pid", "hostname", "goroutine"} {
+		if _, ok := e.Fields[k]; !ok {
+			t.Errorf("field %s missing from %v", k, e.Fields)
+		}
+	}
+	if id, _ := e.Fields["goroutine"].(uint64); id == 0 {
+		t.Errorf("goroutine = %v, want the id of the test goroutine", e.Fields["goroutine"])
+	}
+}
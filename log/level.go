@@ -0,0 +1,171 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// namedLevels holds the levels set for named loggers, the zero value
+// has none set.
+type namedLevels struct {
+	sync.RWMutex
+	levels map[string]Level
+}
+
+// lookup returns the level for `name`, falling back to its parents,
+// "a.b.c" tries "a.b.c", "a.b" and "a".
+func (n *namedLevels) lookup(name string) (Level, bool) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if len(n.levels) == 0 {
+		return 0, false
+	}
+
+	for {
+		if level, ok := n.levels[name]; ok {
+			return level, true
+		}
+
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+func (n *namedLevels) set(name string, level Level) {
+	n.Lock()
+	if n.levels == nil {
+		n.levels = map[string]Level{}
+	}
+	n.levels[name] = level
+	n.Unlock()
+}
+
+func (n *namedLevels) reset(levels map[string]Level) {
+	n.Lock()
+	n.levels = levels
+	n.Unlock()
+}
+
+// GetLevel returns the level used by the logger.
+func (l *Logger) GetLevel() Level {
+	if l.name != "" {
+		if level, ok := l.core().levels.lookup(l.name); ok {
+			return level
+		}
+	}
+	return Level(atomic.LoadInt32(&l.core().level))
+}
+
+// SetLevel sets the level of the logger, for a named logger only the
+// level of that name is changed. It is safe for concurrent use.
+func (l *Logger) SetLevel(level Level) {
+	if l.name != "" {
+		l.core().levels.set(l.name, level)
+		return
+	}
+	atomic.StoreInt32(&l.core().level, int32(level))
+}
+
+// Enabled reports whether entries at `level` are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.GetLevel()
+}
+
+// SetLevels sets the root and per-name levels from a comma separated
+// spec such as "info,nats=debug,scheduler=warn". A bare level sets the
+// root level and names not in the spec fall back to it. The spec
+// replaces all previously set per-name levels.
+func (l *Logger) SetLevels(spec string) error {
+	root := l.core()
+	level := root.GetLevel()
+	levels := map[string]Level{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			v, err := ParseLevel(kv[0])
+			if err != nil {
+				return err
+			}
+			level = v
+			continue
+		}
+
+		name := strings.TrimSpace(kv[0])
+		if name == "" {
+			return fmt.Errorf("missing logger name in %q", part)
+		}
+
+		v, err := ParseLevel(kv[1])
+		if err != nil {
+			return err
+		}
+		levels[name] = v
+	}
+
+	root.levels.reset(levels)
+	root.SetLevel(level)
+	return nil
+}
+
+// LevelSpec returns the current levels in the format read by SetLevels.
+func (l *Logger) LevelSpec() string {
+	root := l.core()
+	parts := []string{levelName(root.GetLevel())}
+
+	root.levels.RLock()
+	names := make([]string, 0, len(root.levels.levels))
+	for name := range root.levels.levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+levelName(root.levels.levels[name]))
+	}
+	root.levels.RUnlock()
+
+	return strings.Join(parts, ",")
+}
+
+// ParseLevel returns the level named `s`, case insensitive.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, level := range Levels {
+		if s == strings.ToLower(level.Name) {
+			return level.Level, nil
+		}
+	}
+	return FATAL, fmt.Errorf("invalid level name (%s)", s)
+}
+
+// levelName returns the lower case name of `level`.
+func levelName(level Level) string {
+	return strings.ToLower(Levels[level].Name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(levelName(l)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
@@ -0,0 +1,57 @@
+package log_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+func TestLevelsOfLoggerLiteral(t *testing.T) {
+	l := &log.Logger{Mutex: new(sync.Mutex)}
+	named := l.Named("nats")
+
+	named.SetLevel(log.DEBUG)
+	if named.GetLevel() != log.DEBUG || l.GetLevel() != log.FATAL {
+		t.Errorf("levels are %v and %v, want DEBUG for nats only", named.GetLevel(), l.GetLevel())
+	}
+
+	if err := l.SetLevels("warn,nats=error"); err != nil {
+		t.Fatal(err)
+	}
+	if named.GetLevel() != log.ERROR || l.GetLevel() != log.WARN {
+		t.Errorf("levels are %v and %v, want ERROR and WARN", named.GetLevel(), l.GetLevel())
+	}
+	if spec := named.LevelSpec(); spec != "warn,nats=error" {
+		t.Errorf("LevelSpec = %q, want warn,nats=error", spec)
+	}
+}
+
+func TestWriteDropsDisabledLevels(t *testing.T) {
+	l, r := logtest.NewLogger()
+	l.SetLevel(log.INFO)
+	named := l.Named("nats")
+	nested := named.Named("conn")
+
+	for _, logger := range []*log.Logger{l, named, nested} {
+		logger.Write(log.DEBUG, log.NewEntry(logger), "debug", 0)
+		logger.Write(log.INFO, log.NewEntry(logger), "info", 0)
+	}
+
+	if r.HasMessage("debug") {
+		t.Error("DEBUG entry written at level INFO")
+	}
+	if n := len(r.Filter(log.INFO)); n != 3 {
+		t.Errorf("%d INFO entries written, want 3", n)
+	}
+
+	// a named level overrides the root level for its children only
+	named.SetLevel(log.DEBUG)
+	nested.Write(log.DEBUG, log.NewEntry(nested), "nested debug", 0)
+	l.Write(log.DEBUG, log.NewEntry(l), "root debug", 0)
+
+	if !r.HasMessage("nested debug") || r.HasMessage("root debug") {
+		t.Errorf("entries %v, want only the nested DEBUG entry", r.Entries())
+	}
+}
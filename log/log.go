@@ -5,8 +5,6 @@ import (
 	"sync"
 	"time"
 
-	stdlog "log"
-
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 )
@@ -48,10 +46,72 @@ type Reporter interface {
 	Write(e *Entry, calldepth int) error
 }
 
+// Logger writes entries at or above its level to its reporters.
+//
+// Named loggers created with Named share the Mutex, Reporters and
+// per-name levels of the logger they were created from. A Logger literal
+// only needs its Mutex set.
+//
+// The level is read and changed with GetLevel and SetLevel, which replace
+// the former exported Level field so it can be changed while logging.
 type Logger struct {
 	*sync.Mutex
-	Level     Level
 	Reporters []Reporter
+
+	// Formatter fills Entry.Formatted before the hooks run,
+	// DefaultFormatter is used when nil.
+	Formatter Formatter
+
+	// Redactor masks sensitive data before the hooks and reporters see
+	// the entry, nothing is masked when nil.
+	Redactor *Redactor
+
+	// Sampler drops repeated entries, nothing is dropped when nil.
+	Sampler *Sampler
+
+	hooks      []Hook
+	metrics    Metrics
+	level      int32
+	stackLevel int32
+	name       string
+	root       *Logger
+	levels     namedLevels
+}
+
+// Named returns a sub-logger called `name`, nested names are joined
+// with a dot. Entries written through it get a "logger" field and are
+// filtered by the level set for that name, see SetLevels.
+func (l *Logger) Named(name string) *Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+
+	return &Logger{
+		Mutex: l.Mutex,
+		name:  name,
+		root:  l.core(),
+	}
+}
+
+// Name returns the name of the logger, empty for the root logger.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// formatter returns the formatter used for hooks.
+func (l *Logger) formatter() Formatter {
+	if l.Formatter != nil {
+		return l.Formatter
+	}
+	return DefaultFormatter
+}
+
+// core returns the root logger holding the reporters.
+func (l *Logger) core() *Logger {
+	if l.root != nil {
+		return l.root
+	}
+	return l
 }
 
 //
@@ -131,26 +191,63 @@ func (l *Logger) Trace(msg string) *Entry {
 }
 
 func (l *Logger) Write(level Level, e *Entry, msg string, calldepth int) *Logger {
-	if e.Level > l.Level {
+	if !l.Enabled(level) {
 		return l
 	}
 
+	root := l.core()
+	root.metrics.logged(level)
+	if s := root.Sampler; s != nil && !s.Sample(level, msg) {
+		root.metrics.drop(DroppedSampled)
+		return l
+	}
+
+	return l.write(level, e, msg, calldepth+1)
+}
+
+// write finalizes `e` and hands it to the hooks and reporters.
+func (l *Logger) write(level Level, e *Entry, msg string, calldepth int) *Logger {
+	// a panicking hook or reporter must not leave the shared mutex locked
 	l.Lock()
+	defer l.Unlock()
 
 	e.Timestamp = time.Now()
 	finished := e.finalize(level, msg)
+	if l.name != "" {
+		if _, ok := finished.Fields["logger"]; !ok {
+			finished.Fields["logger"] = l.name
+		}
+	}
+
+	root := l.core()
+	if level <= root.GetStackLevel() {
+		if _, ok := finished.Fields["callstack"]; !ok {
+			finished.Fields["callstack"] = callStack()
+		}
+	}
+
+	if root.Redactor != nil {
+		root.Redactor.Redact(finished)
+	}
+
+	if len(root.hooks) > 0 {
+		finished.Formatted = formatData(root.formatter(), finished, calldepth+1)
+		if !root.fire(finished) {
+			root.metrics.drop(DroppedHook)
+			return l
+		}
+	}
 
 	var result error
-	for _, r := range l.Reporters {
-		if err := r.Write(finished, calldepth+1); err != nil {
+	for _, r := range root.Reporters {
+		if err := root.metrics.report(r, finished, calldepth+1); err != nil {
 			result = multierror.Append(result, err)
 		}
 	}
 
 	if result != nil {
-		stdlog.Printf("error logging: %s", result)
+		stderr.Printf("error logging: %s", result)
 	}
-	l.Unlock()
 
 	return l
 }
@@ -0,0 +1,210 @@
+// Package logtest records log entries in memory so tests can check what
+// was logged.
+//
+//	func TestSync(t *testing.T) {
+//		rec := logtest.Capture(t)
+//		sync(client)
+//		if !rec.Has(log.OnlyLevels(log.ERROR), log.FieldEquals("client_id", client.ID)) {
+//			t.Error("sync failure was not logged")
+//		}
+//	}
+package logtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+// Recorder is a reporter keeping every entry written to it. It is safe
+// for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []*log.Entry
+}
+
+// New returns an empty recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+// NewLogger returns a logger writing entries of every level to a new
+// recorder.
+func NewLogger() (*log.Logger, *Recorder) {
+	r := New()
+	return log.NewLogger(log.DEBUG, []log.Reporter{r}), r
+}
+
+// Write records a copy of `e`.
+func (r *Recorder) Write(e *log.Entry, calldepth int) error {
+	entry := *e
+	entry.Fields = make(log.Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		entry.Fields[k] = v
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, &entry)
+	r.mu.Unlock()
+	return nil
+}
+
+// Entries returns the recorded entries in the order they were written.
+func (r *Recorder) Entries() []*log.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*log.Entry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Len returns the number of recorded entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries)
+}
+
+// Last returns the last recorded entry, nil when there is none.
+func (r *Recorder) Last() *log.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) == 0 {
+		return nil
+	}
+	return r.entries[len(r.entries)-1]
+}
+
+// Reset forgets the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// Match returns the recorded entries passing all `filters`.
+func (r *Recorder) Match(filters ...log.Filter) []*log.Entry {
+	pass := log.All(filters...)
+
+	var entries []*log.Entry
+	for _, e := range r.Entries() {
+		if pass(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Has reports whether an entry passing all `filters` was recorded.
+func (r *Recorder) Has(filters ...log.Filter) bool {
+	return len(r.Match(filters...)) > 0
+}
+
+// Filter returns the recorded entries at `level`.
+func (r *Recorder) Filter(level log.Level) []*log.Entry {
+	return r.Match(log.OnlyLevels(level))
+}
+
+// HasMessage reports whether an entry with the message `msg` was
+// recorded.
+func (r *Recorder) HasMessage(msg string) bool {
+	return r.Has(func(e *log.Entry) bool { return e.Message == msg })
+}
+
+// FieldEquals reports whether an entry with the field `key` equal to
+// `value` was recorded.
+func (r *Recorder) FieldEquals(key string, value interface{}) bool {
+	return r.Has(log.FieldEquals(key, value))
+}
+
+// swap is a logger set by Swap.
+type swap struct {
+	owner    string // name of the test that set it
+	previous *log.Logger
+}
+
+var (
+	swapMu   sync.Mutex
+	swapDone = sync.NewCond(&swapMu)
+	// swaps are the active swaps, innermost last
+	swaps []swap
+)
+
+// within reports whether the test `name` is the test `owner` or one of
+// its subtests.
+func within(name, owner string) bool {
+	return name == owner || strings.HasPrefix(name, owner+"/")
+}
+
+// Swap makes `l` the default logger, see log.SetDefault, until the test
+// ends. Tests swapping it run one at a time, even when parallel, so each
+// sees only its own entries. A subtest of a test that swapped the logger
+// can swap it again, the logger of the parent test is back once the
+// subtest ends.
+func Swap(tb testing.TB, l *log.Logger) {
+	tb.Helper()
+	name := tb.Name()
+
+	swapMu.Lock()
+	for len(swaps) > 0 && !within(name, swaps[len(swaps)-1].owner) {
+		swapDone.Wait()
+	}
+	swaps = append(swaps, swap{owner: name, previous: log.SetDefault(l)})
+	swapMu.Unlock()
+
+	tb.Cleanup(func() {
+		swapMu.Lock()
+		defer swapMu.Unlock()
+
+		s := swaps[len(swaps)-1]
+		swaps = swaps[:len(swaps)-1]
+		log.SetDefault(s.previous)
+		swapDone.Broadcast()
+	})
+}
+
+// Capture swaps the default logger for one recording every entry until
+// the test ends, see Swap.
+func Capture(tb testing.TB) *Recorder {
+	tb.Helper()
+
+	l, r := NewLogger()
+	Swap(tb, l)
+	return r
+}
+
+// tbReporter is a reporter writing entries to the log of a test.
+type tbReporter struct {
+	tb testing.TB
+}
+
+// NewTB returns a reporter writing entries to the log of the test with
+// tb.Log, as "file:line LEVEL message key=value...". The output is only
+// shown when the test fails or runs with -v.
+//
+//	l := log.NewLogger(log.DEBUG, []log.Reporter{logtest.NewTB(t)})
+func NewTB(tb testing.TB) log.Reporter {
+	return tbReporter{tb: tb}
+}
+
+func (r tbReporter) Write(e *log.Entry, calldepth int) error {
+	var b strings.Builder
+	if frame, ok := e.Caller(); ok {
+		fmt.Fprintf(&b, "%s:%d ", filepath.Base(frame.File), frame.Line)
+	}
+	level, _ := e.Level.MarshalText()
+	fmt.Fprintf(&b, "%s %s", strings.ToUpper(string(level)), e.Message)
+	for _, name := range e.Fields.Names() {
+		fmt.Fprintf(&b, " %s=%v", name, e.Fields[name])
+	}
+
+	r.tb.Log(b.String())
+	return nil
+}
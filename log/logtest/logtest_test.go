@@ -0,0 +1,157 @@
+package logtest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+func TestRecorder(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	l.WithField("id", 1).Debug("one")
+	l.WithField("id", 2).Error("two")
+	l.Info("three")
+
+	if rec.Len() != 3 || len(rec.Entries()) != 3 {
+		t.Fatalf("recorded %d entries, want 3", rec.Len())
+	}
+	if last := rec.Last(); last.Message != "three" {
+		t.Errorf("Last = %q, want three", last.Message)
+	}
+	if got := rec.Filter(log.ERROR); len(got) != 1 || got[0].Message != "two" {
+		t.Errorf("Filter(ERROR) = %v, want the entry two", got)
+	}
+	if !rec.HasMessage("one") || rec.HasMessage("four") {
+		t.Error("HasMessage does not match the messages")
+	}
+	if !rec.FieldEquals("id", 2) || rec.FieldEquals("id", 3) {
+		t.Error("FieldEquals does not match the fields")
+	}
+	if got := rec.Match(log.MinLevel(log.INFO), log.HasField("id")); len(got) != 1 {
+		t.Errorf("Match = %v, want the entry two", got)
+	}
+
+	rec.Reset()
+	if rec.Len() != 0 || rec.Last() != nil {
+		t.Error("Reset kept entries")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	before := log.Default()
+
+	t.Run("swapped", func(t *testing.T) {
+		rec := logtest.Capture(t)
+		log.Info("captured")
+
+		if log.Default() == before || !rec.HasMessage("captured") {
+			t.Error("the package-level functions do not write to the recorder")
+		}
+	})
+
+	if log.Default() != before {
+		t.Error("the default logger was not restored")
+	}
+}
+
+func TestCaptureNested(t *testing.T) {
+	outer := logtest.Capture(t)
+	log.Info("outer")
+
+	t.Run("inner", func(t *testing.T) {
+		inner := logtest.Capture(t)
+		log.Info("inner")
+
+		if !inner.HasMessage("inner") || inner.HasMessage("outer") {
+			t.Errorf("inner recorded %v", inner.Entries())
+		}
+	})
+
+	log.Info("outer again")
+	if outer.Len() != 2 || outer.HasMessage("inner") {
+		t.Errorf("outer recorded %v", outer.Entries())
+	}
+}
+
+func TestCaptureParallel(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			rec := logtest.Capture(t)
+			log.Infof("entry %d", i)
+
+			if rec.Len() != 1 || !rec.HasMessage(fmt.Sprintf("entry %d", i)) {
+				t.Errorf("recorded %v", rec.Entries())
+			}
+		})
+	}
+}
+
+func TestCaptureWhileLogging(t *testing.T) {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				log.Debug("background")
+			}
+		}
+	}()
+	defer wg.Wait()
+	defer close(stop)
+
+	for i := 0; i < 10; i++ {
+		t.Run("swap", func(t *testing.T) {
+			logtest.Capture(t)
+		})
+	}
+}
+
+// fakeTB records what is passed to Log.
+type fakeTB struct {
+	testing.TB
+	logs []string
+}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func TestNewTB(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	l := log.NewLogger(log.DEBUG, []log.Reporter{logtest.NewTB(tb)})
+
+	l.WithField("id", 7).Warn("careful")
+
+	if len(tb.logs) != 1 {
+		t.Fatalf("logged %q, want one line", tb.logs)
+	}
+	got := tb.logs[0]
+	if !strings.HasPrefix(got, "logtest_test.go:") || !strings.HasSuffix(got, " WARN careful id=7") {
+		t.Errorf("logged %q, want logtest_test.go:<line> WARN careful id=7", got)
+	}
+}
+
+func TestCaptureContext(t *testing.T) {
+	rec := logtest.Capture(t)
+
+	log.FromContext(context.Background()).Info("from context")
+	log.WithContext(context.Background()).Info("with context")
+
+	if !rec.HasMessage("from context") || !rec.HasMessage("with context") {
+		t.Errorf("recorded %v, want both context entries", rec.Entries())
+	}
+}
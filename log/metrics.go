@@ -0,0 +1,333 @@
+package log
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the reporter write latency
+// histogram.
+var latencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Drop reasons counted by Metrics.
+const (
+	DroppedSampled   = "sampled"    // dropped by the Sampler
+	DroppedHook      = "hook"       // dropped by a hook returning ErrDrop
+	DroppedRateLimit = "rate_limit" // dropped by a RateLimited reporter
+)
+
+// Metrics counts the entries a logger and its named loggers write. It is
+// safe for concurrent use.
+type Metrics struct {
+	levels     [DEBUG + 1]uint64
+	hookErrors uint64
+
+	dropped   sync.Map // reason -> *uint64
+	reporters sync.Map // reporterKey -> *reporterMetrics
+}
+
+// reporterMetrics are the counters of a single reporter.
+type reporterMetrics struct {
+	name    string
+	entries uint64
+	errors  uint64
+	nanos   uint64
+	buckets [len(latencyBuckets) + 1]uint64
+}
+
+// Metrics returns the counters of the logger, shared with its named
+// loggers.
+func (l *Logger) Metrics() *Metrics {
+	return &l.core().metrics
+}
+
+// logged counts an entry at `level`.
+func (m *Metrics) logged(level Level) {
+	if m == nil || level < FATAL || level > DEBUG {
+		return
+	}
+	atomic.AddUint64(&m.levels[level], 1)
+}
+
+// drop counts an entry dropped for `reason`.
+func (m *Metrics) drop(reason string) {
+	if m == nil {
+		return
+	}
+	n, ok := m.dropped.Load(reason)
+	if !ok {
+		n, _ = m.dropped.LoadOrStore(reason, new(uint64))
+	}
+	atomic.AddUint64(n.(*uint64), 1)
+}
+
+// hookFailed counts a hook returning an error or panicking.
+func (m *Metrics) hookFailed() {
+	if m == nil {
+		return
+	}
+	atomic.AddUint64(&m.hookErrors, 1)
+}
+
+// reporterKey returns the key the counters of `r` are kept under, each
+// configured reporter or else the Go type of the reporter.
+func reporterKey(r Reporter) interface{} {
+	if c, ok := r.(*configured); ok {
+		return c
+	}
+	return reflect.TypeOf(r)
+}
+
+// reporterName returns the name `r` is counted as, the type of a
+// configured reporter or the Go type of any other, such as "cli.Cli".
+// Reporters of the same name are counted together.
+func reporterName(r Reporter) string {
+	if c, ok := r.(*configured); ok && c.name != "" {
+		return c.name
+	}
+	return strings.TrimPrefix(reflect.TypeOf(r).String(), "*")
+}
+
+// reporter returns the counters of `r`.
+func (m *Metrics) reporter(r Reporter) *reporterMetrics {
+	key := reporterKey(r)
+	rm, ok := m.reporters.Load(key)
+	if !ok {
+		rm, _ = m.reporters.LoadOrStore(key, &reporterMetrics{name: reporterName(r)})
+	}
+	return rm.(*reporterMetrics)
+}
+
+// wanter is a reporter wrapper that can tell whether it passes an entry
+// on, such as Filtered, so entries it drops aren't counted as written.
+type wanter interface {
+	wants(e *Entry) bool
+}
+
+// wants reports whether `r` passes `e` on, true unless `r` is a wanter.
+func wants(r Reporter, e *Entry) bool {
+	if w, ok := r.(wanter); ok {
+		return w.wants(e)
+	}
+	return true
+}
+
+// report writes `e` to `r`, counting the write, its latency and error.
+// Entries the level of a configured reporter or its filters drop are not
+// counted.
+func (m *Metrics) report(r Reporter, e *Entry, calldepth int) error {
+	if m == nil {
+		return r.Write(e, calldepth+1)
+	}
+	if !wants(r, e) {
+		return nil
+	}
+
+	start := time.Now()
+	err := r.Write(e, calldepth+1)
+	took := time.Since(start)
+
+	rm := m.reporter(r)
+	atomic.AddUint64(&rm.entries, 1)
+	atomic.AddUint64(&rm.nanos, uint64(took))
+	i := 0
+	for i < len(latencyBuckets) && took > latencyBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&rm.buckets[i], 1)
+	if err != nil {
+		atomic.AddUint64(&rm.errors, 1)
+	}
+	return err
+}
+
+// MetricsSnapshot holds the counters of a logger at one point in time.
+type MetricsSnapshot struct {
+	// Entries are the entries logged per level name, including the
+	// ones dropped afterwards.
+	Entries map[string]uint64 `json:"entries"`
+	// Dropped are the entries not written per reason, see DroppedSampled.
+	Dropped map[string]uint64 `json:"dropped"`
+	// HookErrors are the hooks that failed or panicked.
+	HookErrors uint64 `json:"hook_errors"`
+	// Reporters are the counters per reporter name.
+	Reporters map[string]ReporterSnapshot `json:"reporters"`
+}
+
+// ReporterSnapshot holds the counters of a reporter.
+type ReporterSnapshot struct {
+	Entries uint64 `json:"entries"`
+	Errors  uint64 `json:"errors"`
+	// Latency is the total time spent writing.
+	Latency time.Duration `json:"latency_ns"`
+	// Buckets count the writes by latency, Buckets[i] are the writes
+	// taking up to LatencyBuckets()[i] and the last one the slower ones.
+	Buckets []uint64 `json:"buckets"`
+}
+
+// LatencyBuckets returns the upper bounds of the latency buckets.
+func LatencyBuckets() []time.Duration {
+	return append([]time.Duration(nil), latencyBuckets[:]...)
+}
+
+// Snapshot returns the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Entries:   map[string]uint64{},
+		Dropped:   map[string]uint64{},
+		Reporters: map[string]ReporterSnapshot{},
+	}
+	if m == nil {
+		return s
+	}
+
+	for level := FATAL; level <= DEBUG; level++ {
+		s.Entries[levelName(level)] = atomic.LoadUint64(&m.levels[level])
+	}
+	s.HookErrors = atomic.LoadUint64(&m.hookErrors)
+
+	m.dropped.Range(func(reason, n interface{}) bool {
+		s.Dropped[reason.(string)] = atomic.LoadUint64(n.(*uint64))
+		return true
+	})
+
+	m.reporters.Range(func(_, v interface{}) bool {
+		rm := v.(*reporterMetrics)
+		rs := s.Reporters[rm.name]
+		rs.Entries += atomic.LoadUint64(&rm.entries)
+		rs.Errors += atomic.LoadUint64(&rm.errors)
+		rs.Latency += time.Duration(atomic.LoadUint64(&rm.nanos))
+		if rs.Buckets == nil {
+			rs.Buckets = make([]uint64, len(rm.buckets))
+		}
+		for i := range rm.buckets {
+			rs.Buckets[i] += atomic.LoadUint64(&rm.buckets[i])
+		}
+		s.Reporters[rm.name] = rs
+		return true
+	})
+	return s
+}
+
+// WritePrometheus writes the counters in the Prometheus text format:
+//
+//	log_entries_total{level="error"} 12
+//	log_dropped_total{reason="sampled"} 340
+//	log_hook_errors_total 0
+//	log_reporter_entries_total{reporter="file"} 1204
+//	log_reporter_errors_total{reporter="file"} 0
+//	log_reporter_write_seconds_bucket{reporter="file",le="0.0001"} 1190
+//	log_reporter_write_seconds_sum{reporter="file"} 0.0412
+//	log_reporter_write_seconds_count{reporter="file"} 1204
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# HELP log_entries_total Entries logged per level.\n")
+	b.WriteString("# TYPE log_entries_total counter\n")
+	for level := FATAL; level <= DEBUG; level++ {
+		name := levelName(level)
+		fmt.Fprintf(&b, "log_entries_total{level=%q} %d\n", name, s.Entries[name])
+	}
+
+	b.WriteString("# HELP log_dropped_total Entries dropped before reaching the reporters.\n")
+	b.WriteString("# TYPE log_dropped_total counter\n")
+	for _, reason := range sortedKeys(s.Dropped) {
+		fmt.Fprintf(&b, "log_dropped_total{reason=%q} %d\n", reason, s.Dropped[reason])
+	}
+
+	b.WriteString("# HELP log_hook_errors_total Hooks that failed or panicked.\n")
+	b.WriteString("# TYPE log_hook_errors_total counter\n")
+	fmt.Fprintf(&b, "log_hook_errors_total %d\n", s.HookErrors)
+
+	names := make([]string, 0, len(s.Reporters))
+	for name := range s.Reporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("# HELP log_reporter_entries_total Entries written per reporter.\n")
+	b.WriteString("# TYPE log_reporter_entries_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "log_reporter_entries_total{reporter=%q} %d\n", name, s.Reporters[name].Entries)
+	}
+
+	b.WriteString("# HELP log_reporter_errors_total Failed writes per reporter.\n")
+	b.WriteString("# TYPE log_reporter_errors_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "log_reporter_errors_total{reporter=%q} %d\n", name, s.Reporters[name].Errors)
+	}
+
+	b.WriteString("# HELP log_reporter_write_seconds Time spent writing an entry per reporter.\n")
+	b.WriteString("# TYPE log_reporter_write_seconds histogram\n")
+	for _, name := range names {
+		rs := s.Reporters[name]
+		var count uint64
+		for i, n := range rs.Buckets {
+			count += n
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = strconv.FormatFloat(latencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "log_reporter_write_seconds_bucket{reporter=%q,le=%q} %d\n", name, le, count)
+		}
+		fmt.Fprintf(&b, "log_reporter_write_seconds_sum{reporter=%q} %g\n", name, rs.Latency.Seconds())
+		fmt.Fprintf(&b, "log_reporter_write_seconds_count{reporter=%q} %d\n", name, count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MetricsHandler returns a http.Handler serving the counters of `l` in
+// the Prometheus text format, or as JSON with ?format=json.
+//
+//	http.Handle("/metrics/log", log.MetricsHandler(log.Log))
+func MetricsHandler(l *Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := l.Metrics().Snapshot()
+
+		if r.FormValue("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.WritePrometheus(w)
+	})
+}
+
+// PublishMetrics publishes the counters of `l` with expvar as `name`, so
+// they are served on /debug/vars. Like expvar.Publish it panics when the
+// name is already used.
+func PublishMetrics(name string, l *Logger) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return l.Metrics().Snapshot()
+	}))
+}
@@ -0,0 +1,198 @@
+package log_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+// reporterEntries returns the entries counted for the only reporter.
+func reporterEntries(t *testing.T, s log.MetricsSnapshot) log.ReporterSnapshot {
+	t.Helper()
+
+	if len(s.Reporters) != 1 {
+		t.Fatalf("reporters = %v, want one", s.Reporters)
+	}
+	for _, rs := range s.Reporters {
+		return rs
+	}
+	return log.ReporterSnapshot{}
+}
+
+func TestMetricsOfLoggerLiteral(t *testing.T) {
+	l := &log.Logger{Mutex: new(sync.Mutex), Reporters: []log.Reporter{logtest.New()}}
+	l.SetLevel(log.DEBUG)
+
+	l.Info("one")
+	l.Named("nats").Info("two")
+	l.Error("three")
+
+	s := l.Metrics().Snapshot()
+	if s.Entries["info"] != 2 || s.Entries["error"] != 1 {
+		t.Errorf("Entries = %v, want 2 info and 1 error", s.Entries)
+	}
+	if rs := reporterEntries(t, s); rs.Entries != 3 {
+		t.Errorf("reporter entries = %d, want 3", rs.Entries)
+	}
+}
+
+func TestMetricsFilteredEntries(t *testing.T) {
+	mux := log.NewMux().Route(logtest.New(), log.MinLevel(log.ERROR))
+	for name, r := range map[string]log.Reporter{
+		"Filtered": log.Filtered(logtest.New(), log.MinLevel(log.WARN)),
+		"Mux":      mux,
+	} {
+		l := log.NewLogger(log.DEBUG, []log.Reporter{r})
+		l.Info("info")
+		l.Warn("warn")
+		l.Error("error")
+
+		want := map[string]uint64{"Filtered": 2, "Mux": 1}[name]
+		if rs := reporterEntries(t, l.Metrics().Snapshot()); rs.Entries != want {
+			t.Errorf("%s: reporter entries = %d, want %d", name, rs.Entries, want)
+		}
+	}
+}
+
+func TestMetricsDropped(t *testing.T) {
+	l := log.NewLogger(log.DEBUG, []log.Reporter{log.RateLimited(logtest.New(), 0.001, 1)})
+	sampler, err := log.NewSampler(time.Hour, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Sampler = sampler
+	l.AddHook(log.HookFunc(func(e *log.Entry) error {
+		if e.Message == "drop" {
+			return log.ErrDrop
+		}
+		return nil
+	}))
+
+	for i := 0; i < 4; i++ {
+		l.Info("sampled")
+	}
+	l.Info("drop")
+
+	s := l.Metrics().Snapshot()
+	want := map[string]uint64{log.DroppedSampled: 1, log.DroppedHook: 1, log.DroppedRateLimit: 2}
+	for reason, n := range want {
+		if s.Dropped[reason] != n {
+			t.Errorf("Dropped = %v, want %v", s.Dropped, want)
+			break
+		}
+	}
+	if s.Entries["info"] != 5 {
+		t.Errorf("info entries = %d, want 5", s.Entries["info"])
+	}
+}
+
+// slow is a reporter taking `delay` for every write.
+type slow struct{ delay time.Duration }
+
+func (s slow) Write(e *log.Entry, calldepth int) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func TestMetricsLatency(t *testing.T) {
+	l := log.NewLogger(log.DEBUG, []log.Reporter{slow{2 * time.Millisecond}})
+	l.Info("slow")
+
+	rs := reporterEntries(t, l.Metrics().Snapshot())
+	if rs.Latency < 2*time.Millisecond {
+		t.Errorf("Latency = %v, want at least 2ms", rs.Latency)
+	}
+
+	buckets := log.LatencyBuckets()
+	if len(rs.Buckets) != len(buckets)+1 {
+		t.Fatalf("%d buckets, want %d", len(rs.Buckets), len(buckets)+1)
+	}
+	for i, n := range rs.Buckets {
+		inBucket := (i == len(buckets) || rs.Latency <= buckets[i]) && (i == 0 || rs.Latency > buckets[i-1])
+		if inBucket != (n == 1) {
+			t.Errorf("bucket %d counts %d for a write of %v", i, n, rs.Latency)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	s := log.MetricsSnapshot{
+		Entries: map[string]uint64{"error": 2, "info": 5},
+		Dropped: map[string]uint64{log.DroppedSampled: 3},
+		Reporters: map[string]log.ReporterSnapshot{
+			"file": {Entries: 7, Errors: 1, Latency: 1500 * time.Microsecond, Buckets: []uint64{4, 2, 1, 0, 0, 0, 0}},
+		},
+	}
+
+	var b strings.Builder
+	if err := s.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		`log_entries_total{level="fatal"} 0`,
+		`log_entries_total{level="error"} 2`,
+		`log_entries_total{level="info"} 5`,
+		`log_dropped_total{reason="sampled"} 3`,
+		`log_hook_errors_total 0`,
+		`log_reporter_entries_total{reporter="file"} 7`,
+		`log_reporter_errors_total{reporter="file"} 1`,
+		`log_reporter_write_seconds_bucket{reporter="file",le="1e-05"} 4`,
+		`log_reporter_write_seconds_bucket{reporter="file",le="0.0001"} 6`,
+		`log_reporter_write_seconds_bucket{reporter="file",le="+Inf"} 7`,
+		`log_reporter_write_seconds_sum{reporter="file"} 0.0015`,
+		`log_reporter_write_seconds_count{reporter="file"} 7`,
+		`# TYPE log_reporter_write_seconds histogram`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing %s in\n%s", line, b.String())
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	l, _ := logtest.NewLogger()
+	l.Warn("warn")
+
+	w := httptest.NewRecorder()
+	log.MetricsHandler(l).ServeHTTP(w, httptest.NewRequest("GET", "/?format=json", nil))
+
+	var s log.MetricsSnapshot
+	if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries["warn"] != 1 {
+		t.Errorf("Entries = %v, want 1 warn", s.Entries)
+	}
+
+	w = httptest.NewRecorder()
+	log.MetricsHandler(l).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), `log_entries_total{level="warn"} 1`) {
+		t.Errorf("served\n%s", w.Body.String())
+	}
+}
+
+func TestPublishMetrics(t *testing.T) {
+	l, _ := logtest.NewLogger()
+	l.Info("info")
+
+	// expvar names can't be published twice, so -count=2 needs a new one
+	name := fmt.Sprintf("log_test_metrics_%d", time.Now().UnixNano())
+	log.PublishMetrics(name, l)
+
+	var s log.MetricsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries["info"] != 1 {
+		t.Errorf("Entries = %v, want 1 info", s.Entries)
+	}
+}
@@ -0,0 +1,319 @@
+package log
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options is a declarative reporter configuration, as read from a JSON or
+// YAML section or from the environment.
+//
+// Keys are snake_case field names, "max_size" sets MaxSize. Values may be
+// strings, which are converted to the field type: durations as "24h",
+// sizes as "10MB" for fields named ...Size, file modes as "0644", levels
+// by name and maps as "error=errors.log,warn=warn.log".
+type Options map[string]interface{}
+
+// EnvOptions returns the environment variables starting with `prefix` as
+// options, LOG_FILE_MAX_SIZE=10MB with prefix "LOG_FILE_" gives
+// {"max_size": "10MB"}.
+func EnvOptions(prefix string) Options {
+	o := Options{}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(kv, prefix), "=", 2)
+		if len(parts) == 2 && parts[0] != "" {
+			o[strings.ToLower(parts[0])] = parts[1]
+		}
+	}
+	return o
+}
+
+// Decode sets the fields of the struct pointed to by `target`, unknown
+// keys and values that don't convert are errors.
+func (o Options) Decode(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("options target must be a pointer to a struct, got %T", target)
+	}
+	v = v.Elem()
+
+	for key, value := range o {
+		field, size := optionField(v, key)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("unknown option %q", key)
+		}
+
+		if err := setOption(field, value, size); err != nil {
+			return fmt.Errorf("option %q: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// optionField returns the field named `key` by json tag or as snake_case
+// of the field name, and whether it is a size read with ParseSize.
+func optionField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		size := strings.HasSuffix(f.Name, "Size")
+		if tag := jsonName(f); tag != "" && tag != "-" {
+			if tag == key {
+				return v.Field(i), size
+			}
+			continue
+		}
+		if strings.EqualFold(strings.Replace(key, "_", "", -1), f.Name) {
+			return v.Field(i), size
+		}
+	}
+	return reflect.Value{}, false
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileModeType        = reflect.TypeOf(os.FileMode(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setOption converts `value` to the type of `field` and sets it, strings
+// are read with ParseSize for an integer `size`.
+func setOption(field reflect.Value, value interface{}, size bool) error {
+	if value == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	t := field.Type()
+
+	if val.Type().AssignableTo(t) && t.Kind() != reflect.Map {
+		field.Set(val)
+		return nil
+	}
+
+	s, isString := value.(string)
+	if isString && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case t == durationType && isString:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+
+	case t == fileModeType && isString:
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return err
+		}
+		field.SetUint(m)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		field.SetString(fmt.Sprint(value))
+		return nil
+
+	case reflect.Bool:
+		if isString {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			n, err := parseInt(s, size)
+			if err != nil {
+				return err
+			}
+			field.SetInt(n)
+			return nil
+		}
+		if val.Type().ConvertibleTo(t) && isNumber(val.Kind()) {
+			field.Set(val.Convert(t))
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			n, err := parseInt(s, size)
+			if err != nil {
+				return err
+			}
+			field.SetUint(uint64(n))
+			return nil
+		}
+		if val.Type().ConvertibleTo(t) && isNumber(val.Kind()) {
+			field.Set(val.Convert(t))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if isString {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+			return nil
+		}
+		if val.Type().ConvertibleTo(t) && isNumber(val.Kind()) {
+			field.Set(val.Convert(t))
+			return nil
+		}
+
+	case reflect.Slice:
+		if isString {
+			val = reflect.ValueOf(splitList(s))
+		}
+		if val.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(t, val.Len(), val.Len())
+			for i := 0; i < val.Len(); i++ {
+				if err := setOption(slice.Index(i), val.Index(i).Interface(), size); err != nil {
+					return err
+				}
+			}
+			field.Set(slice)
+			return nil
+		}
+
+	case reflect.Map:
+		if isString {
+			val = reflect.ValueOf(splitMap(s))
+		}
+		if val.Kind() == reflect.Map {
+			m := reflect.MakeMapWithSize(t, val.Len())
+			iter := val.MapRange()
+			for iter.Next() {
+				k := reflect.New(t.Key()).Elem()
+				if err := setOption(k, fmt.Sprint(iter.Key().Interface()), false); err != nil {
+					return err
+				}
+				e := reflect.New(t.Elem()).Elem()
+				if err := setOption(e, iter.Value().Interface(), size); err != nil {
+					return err
+				}
+				m.SetMapIndex(k, e)
+			}
+			field.Set(m)
+			return nil
+		}
+
+	case reflect.Struct:
+		if sub, ok := toOptions(value); ok {
+			return sub.Decode(field.Addr().Interface())
+		}
+	}
+
+	return fmt.Errorf("cannot use %T as %s", value, t)
+}
+
+// toOptions converts the maps JSON and YAML decoders produce to Options.
+func toOptions(value interface{}) (Options, bool) {
+	switch m := value.(type) {
+	case Options:
+		return m, true
+	case map[string]interface{}:
+		return Options(m), true
+	case map[interface{}]interface{}:
+		o := Options{}
+		for k, v := range m {
+			o[fmt.Sprint(k)] = v
+		}
+		return o, true
+	}
+	return nil, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// splitList splits "a,b,c" into its trimmed parts.
+func splitList(s string) []interface{} {
+	var list []interface{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// splitMap splits "a=1,b=2" into a map.
+func splitMap(s string) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, part := range splitList(s) {
+		kv := strings.SplitN(part.(string), "=", 2)
+		if len(kv) == 2 {
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return m
+}
+
+// parseInt parses a size with ParseSize or else a plain number.
+func parseInt(s string, size bool) (int64, error) {
+	if size {
+		return ParseSize(s)
+	}
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+}
+
+// sizeUnits are the suffixes ParseSize understands.
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a number with an optional KB, MB or GB suffix.
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * unit, nil
+}
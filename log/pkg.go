@@ -2,19 +2,59 @@ package log
 
 import (
 	"sync"
-
-	"strings"
+	"sync/atomic"
 )
 
 // singletons ftw?
-var Log = &Logger{
-	Reporters: []Reporter{stdLog{}},
-	Level:     INFO,
+//
+// Log is the default logger of the package-level functions. Assigning it
+// races with goroutines that are logging, use SetDefault to replace it
+// while the program runs.
+var Log = NewLogger(INFO, []Reporter{stdLog{}})
+
+var (
+	// defaultLogger holds the logger set with SetDefault.
+	defaultLogger atomic.Value
+	defaultMu     sync.Mutex
+)
+
+// override wraps the logger in defaultLogger, nil goes back to Log.
+type override struct {
+	logger *Logger
+}
+
+// Default returns the logger the package-level functions write to, the
+// one set with SetDefault or else Log.
+func Default() *Logger {
+	if o, ok := defaultLogger.Load().(override); ok && o.logger != nil {
+		return o.logger
+	}
+	return Log
+}
+
+// SetDefault makes the package-level functions write to `l`, nil makes
+// them use Log again. It returns the logger set before, nil when there
+// was none, and is safe for concurrent use.
+func SetDefault(l *Logger) (previous *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	old, _ := defaultLogger.Load().(override)
+	defaultLogger.Store(override{l})
+	return old.logger
 }
 
 // AddReporter adds a new reporter to the logger
 func AddReporter(r Reporter) {
-	Log.Reporters = append(Log.Reporters, r)
+	root := Default().core()
+	root.Lock()
+	root.Reporters = append(root.Reporters, r)
+	root.Unlock()
+}
+
+// AddHook adds a new hook to the logger
+func AddHook(h Hook) {
+	Default().AddHook(h)
 }
 
 // NewLogger creates a new logger
@@ -22,99 +62,107 @@ func NewLogger(level Level, reporters []Reporter) *Logger {
 	return &Logger{
 		Mutex:     new(sync.Mutex),
 		Reporters: reporters,
-		Level:     level,
+		level:     int32(level),
 	}
 }
 
-// SetLevel sets the log level. This is not thread-safe.
+// SetLevel sets the log level. It is safe for concurrent use.
 func SetLevel(l Level) {
-	Log.Level = l
+	Default().SetLevel(l)
 }
 
-// SetLevelFromString sets the log level from a string, panicing when invalid. This is not thread-safe.
+// SetLevelFromString sets the log level from a string, panicing when invalid.
 func SetLevelFromString(s string) {
-	Log.Level = GetLevelFromString(s)
+	Default().SetLevel(GetLevelFromString(s))
+}
+
+// SetLevels sets the root and per-name levels from a spec such as
+// "info,nats=debug,scheduler=warn".
+func SetLevels(spec string) error {
+	return Default().SetLevels(spec)
 }
 
 // GetLevelFromString returns the log level from a string, panicing when invalid
 func GetLevelFromString(s string) Level {
-	s = strings.ToLower(s)
-	for _, level := range Levels {
-		if s == strings.ToLower(level.Name) {
-			return level.Level
-		}
+	level, err := ParseLevel(s)
+	if err != nil {
+		panic(err.Error())
 	}
-	panic("Invalid level name (" + s + ")")
-	return FATAL
+	return level
+}
+
+// Named returns a named sub-logger of the default logger.
+func Named(name string) *Logger {
+	return Default().Named(name)
 }
 
 // WithFields returns a new entry with `fields` set.
 func WithFields(fields Fielder) *Entry {
-	return NewEntry(Log).WithFields(fields)
+	return NewEntry(Default()).WithFields(fields)
 }
 
 // WithField returns a new entry with the `key` and `value` set.
 func WithField(key string, value interface{}) *Entry {
-	return NewEntry(Log).WithField(key, value)
+	return NewEntry(Default()).WithField(key, value)
 }
 
 // WithError returns a new entry with the "error" set to `err`.
 func WithError(err error) *Entry {
-	return NewEntry(Log).WithError(err)
+	return NewEntry(Default()).WithError(err)
 }
 
 // Debug level message.
 func Debug(msg string) {
-	NewEntry(Log).Debug(msg)
+	NewEntry(Default()).Debug(msg)
 }
 
 // Info level message.
 func Info(msg string) {
-	NewEntry(Log).Info(msg)
+	NewEntry(Default()).Info(msg)
 }
 
 // Warn level message.
 func Warn(msg string) {
-	NewEntry(Log).Warn(msg)
+	NewEntry(Default()).Warn(msg)
 }
 
 // Error level message.
 func Error(msg string) {
-	NewEntry(Log).Error(msg)
+	NewEntry(Default()).Error(msg)
 }
 
 // Fatal level message, followed by an exit.
 func Fatal(msg string) {
-	NewEntry(Log).Fatal(msg)
+	NewEntry(Default()).Fatal(msg)
 }
 
 // Debugf level formatted message.
 func Debugf(msg string, v ...interface{}) {
-	NewEntry(Log).Debugf(msg, v...)
+	NewEntry(Default()).Debugf(msg, v...)
 }
 
 // Infof level formatted message.
 func Infof(msg string, v ...interface{}) {
-	NewEntry(Log).Infof(msg, v...)
+	NewEntry(Default()).Infof(msg, v...)
 }
 
 // Warnf level formatted message.
 func Warnf(msg string, v ...interface{}) {
-	NewEntry(Log).Warnf(msg, v...)
+	NewEntry(Default()).Warnf(msg, v...)
 }
 
 // Errorf level formatted message.
 func Errorf(msg string, v ...interface{}) {
-	NewEntry(Log).Errorf(msg, v...)
+	NewEntry(Default()).Errorf(msg, v...)
 }
 
 // Fatalf level formatted message, followed by an exit.
 func Fatalf(msg string, v ...interface{}) {
-	NewEntry(Log).Fatalf(msg, v...)
+	NewEntry(Default()).Fatalf(msg, v...)
 }
 
 // Trace returns a new entry with a Stop method to fire off
 // a corresponding completion log, useful with defer.
 func Trace(msg string) *Entry {
-	return NewEntry(Log).Trace(msg)
+	return NewEntry(Default()).Trace(msg)
 }
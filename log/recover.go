@@ -0,0 +1,165 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// recoverConfig is how a recovered panic is handled.
+type recoverConfig struct {
+	level   Level
+	rePanic bool
+}
+
+// RecoverOption changes how Recover and Go handle a panic.
+type RecoverOption func(*recoverConfig)
+
+// RecoverLevel logs panics at `level`, ERROR by default. At FATAL the
+// process exits after logging, like Fatal.
+func RecoverLevel(level Level) RecoverOption {
+	return func(c *recoverConfig) { c.level = level }
+}
+
+// RePanic panics again with the same value after logging, instead of
+// continuing.
+func RePanic() RecoverOption {
+	return func(c *recoverConfig) { c.rePanic = true }
+}
+
+// Recover logs a panic with its value, stack and the fields of `e`,
+// flushes the reporters and continues. Use it deferred:
+//
+//	defer log.Recover(log.WithField("subject", msg.Subject))
+//
+// A nil `e` logs to the default logger.
+func Recover(e *Entry, opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		if e == nil {
+			e = NewEntry(Default())
+		}
+		e.handlePanic(v, opts)
+	}
+}
+
+// Recover logs a panic with its value, stack and the fields of the
+// entry, see the package level Recover.
+func (e *Entry) Recover(opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		e.handlePanic(v, opts)
+	}
+}
+
+// Recover logs a panic to the logger, see the package level Recover.
+func (l *Logger) Recover(opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		NewEntry(l).handlePanic(v, opts)
+	}
+}
+
+// Go runs `fn` in a new goroutine, logging its panics to the default
+// logger as Recover does.
+func Go(fn func(), opts ...RecoverOption) {
+	NewEntry(Default()).Go(fn, opts...)
+}
+
+// Go runs `fn` in a new goroutine, logging its panics with the fields
+// of the entry as Recover does.
+func (e *Entry) Go(fn func(), opts ...RecoverOption) {
+	go func() {
+		defer e.Recover(opts...)
+		fn()
+	}()
+}
+
+// Go runs `fn` in a new goroutine, logging its panics to the logger as
+// Recover does.
+func (l *Logger) Go(fn func(), opts ...RecoverOption) {
+	NewEntry(l).Go(fn, opts...)
+}
+
+// handlePanic logs the panic value `v` with the stack of the panicking
+// goroutine.
+func (e *Entry) handlePanic(v interface{}, opts []RecoverOption) {
+	config := recoverConfig{level: ERROR}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	ctx := e
+	if err, ok := v.(error); ok {
+		ctx = ctx.WithError(err)
+	}
+	ctx = ctx.WithFields(Fields{
+		"panic":     fmt.Sprint(v),
+		"callstack": panicStack(),
+	})
+
+	ctx.Logger.Write(config.level, ctx, fmt.Sprintf("panic: %v", v), 1)
+	if err := ctx.Logger.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "error flushing log: %s\n", err)
+	}
+
+	if config.rePanic {
+		panic(v)
+	}
+	if config.level == FATAL {
+		os.Exit(1)
+	}
+}
+
+// panicStack returns the stack of the panicking goroutine, from the
+// function that panicked up.
+func panicStack() Stack {
+	pcs := pcPool.Get().(*[maxCallers]uintptr)
+	defer pcPool.Put(pcs)
+	n := runtime.Callers(1, pcs[:])
+
+	var s Stack
+	frames := runtime.CallersFrames(pcs[:n])
+	panicked := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case !panicked:
+			panicked = frame.Function == "runtime.gopanic"
+		case strings.HasPrefix(frame.Function, "runtime."):
+		default:
+			s = append(s, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			return s
+		}
+	}
+}
+
+// Flush writes the summaries of the Sampler and flushes the reporters
+// that buffer entries, such as the file and cli reporters, including the
+// ones wrapped by Filtered, NewMux and RateLimited.
+func (l *Logger) Flush() error {
+	root := l.core()
+	root.Lock()
+	reporters := root.Reporters
+	sampler := root.Sampler
+	root.Unlock()
+
+	if sampler != nil {
+		sampler.Flush(root)
+	}
+
+	var result error
+	for _, r := range reporters {
+		if err := flushReporter(r); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// Flush flushes the reporters of the default logger.
+func Flush() error {
+	return Default().Flush()
+}
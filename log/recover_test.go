@@ -0,0 +1,108 @@
+package log_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+)
+
+// checkPanicEntry checks the entry logged for the panic "boom".
+func checkPanicEntry(t *testing.T, e *log.Entry, level log.Level) {
+	t.Helper()
+
+	if e == nil {
+		t.Fatal("no entry logged for the panic")
+	}
+	if e.Level != level || e.Message != "panic: boom" || e.Fields["panic"] != "boom" {
+		t.Errorf("entry %+v, want the panic at %v", e, level)
+	}
+
+	stack, ok := e.Fields["callstack"].(log.Stack)
+	if !ok || len(stack) == 0 {
+		t.Fatalf("callstack = %v, want the stack of the panic", e.Fields["callstack"])
+	}
+	if !strings.HasSuffix(stack[0].File, "recover_test.go") {
+		t.Errorf("stack starts at %s, want the panicking function", stack[0].File)
+	}
+}
+
+func TestRecoverContinues(t *testing.T) {
+	r := &flushCloser{Recorder: logtest.New()}
+	l := log.NewLogger(log.DEBUG, []log.Reporter{r})
+
+	func() {
+		defer l.Recover()
+		panic("boom")
+	}()
+
+	checkPanicEntry(t, r.Last(), log.ERROR)
+	if r.flushed != 1 {
+		t.Errorf("reporters flushed %d times, want once", r.flushed)
+	}
+}
+
+func TestRecoverRePanic(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	err := errors.New("boom")
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		defer l.WithField("job", 3).Recover(log.RePanic(), log.RecoverLevel(log.WARN))
+		panic(err)
+	}()
+
+	if recovered != err {
+		t.Errorf("recovered %v, want the original panic value", recovered)
+	}
+	checkPanicEntry(t, rec.Last(), log.WARN)
+	if !rec.FieldEquals("job", 3) || !rec.FieldEquals("error", "boom") {
+		t.Errorf("fields %v, want the entry fields and the error", rec.Last().Fields)
+	}
+}
+
+func TestRecoverWithoutPanic(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	func() {
+		defer l.Recover(log.RePanic())
+	}()
+
+	if rec.Len() != 0 {
+		t.Errorf("recorded %v without a panic", rec.Entries())
+	}
+}
+
+func TestRecoverDefaultLogger(t *testing.T) {
+	rec := logtest.Capture(t)
+
+	func() {
+		defer log.Recover(nil)
+		panic("boom")
+	}()
+
+	checkPanicEntry(t, rec.Last(), log.ERROR)
+}
+
+func TestGo(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	l.WithField("worker", 1).Go(func() { panic("boom") })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for rec.Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the panic of the goroutine was not logged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	checkPanicEntry(t, rec.Last(), log.ERROR)
+	if !rec.FieldEquals("worker", 1) {
+		t.Errorf("fields %v, want the entry fields", rec.Last().Fields)
+	}
+}
@@ -0,0 +1,320 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactedFields are the field names masked by a Redactor created
+// without names.
+var DefaultRedactedFields = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"api_key",
+	"authorization",
+	"cookie",
+	"credentials",
+	"private_key",
+}
+
+var (
+	// EmailPattern matches e-mail addresses.
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// BearerPattern matches bearer tokens in authorization headers.
+	BearerPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// maxRedactDepth limits how deep nested values are walked.
+const maxRedactDepth = 8
+
+// Unredacted marks a field value that should never be redacted. It is
+// rendered as the value it holds.
+type Unredacted struct {
+	Value interface{}
+}
+
+// Format implements fmt.Formatter.
+func (u Unredacted) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, fmt.FormatString(s, verb), u.Value)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (u Unredacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.Value)
+}
+
+var unredactedType = reflect.TypeOf(Unredacted{})
+
+// unredacted reports whether `v` holds an Unredacted value, also behind
+// interfaces such as the values of a map[string]interface{}.
+func unredacted(v reflect.Value) bool {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.IsValid() && v.Type() == unredactedType
+}
+
+// Redactor masks sensitive fields and message parts of entries.
+//
+// Field names are matched case-insensitive and ignoring "_" and "-", so
+// "api_key" also masks "APIKey", and as a suffix, so "password" also
+// masks "db_password". Nested map keys and struct fields (by name or
+// json tag) are masked too. Patterns are replaced in the message
+// and in string values.
+type Redactor struct {
+	// Mask replaces redacted values, "[REDACTED]" when empty.
+	Mask string
+
+	names    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor masking the fields `names`, or the
+// DefaultRedactedFields when none are given.
+func NewRedactor(names ...string) *Redactor {
+	if len(names) == 0 {
+		names = DefaultRedactedFields
+	}
+	r := &Redactor{names: map[string]bool{}}
+	return r.Fields(names...)
+}
+
+// Fields adds field names to mask.
+func (r *Redactor) Fields(names ...string) *Redactor {
+	for _, name := range names {
+		r.names[normalizeName(name)] = true
+	}
+	return r
+}
+
+// Pattern adds patterns to replace in messages and string values.
+func (r *Redactor) Pattern(patterns ...*regexp.Regexp) *Redactor {
+	r.patterns = append(r.patterns, patterns...)
+	return r
+}
+
+// Fire implements Hook so the redactor can also run between other hooks.
+func (r *Redactor) Fire(e *Entry) error {
+	r.Redact(e)
+	return nil
+}
+
+// Redact masks the message and fields of `e` in place. Field values are
+// replaced by redacted copies, the original values are not modified.
+func (r *Redactor) Redact(e *Entry) {
+	e.Message = r.redactString(e.Message)
+
+	for k, v := range e.Fields {
+		if _, ok := v.(Unredacted); ok {
+			continue
+		}
+		if r.sensitive(k) {
+			e.Fields[k] = r.mask()
+			continue
+		}
+		if v == nil {
+			continue
+		}
+
+		if rv, ok := r.redactValue(reflect.ValueOf(v), 0); ok {
+			e.Fields[k] = rv.Interface()
+		}
+	}
+
+	if e.Formatted != nil {
+		e.Formatted["Message"] = e.Message
+	}
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return "[REDACTED]"
+	}
+	return r.Mask
+}
+
+// sensitive reports whether the field `name` ends with a redacted name.
+func (r *Redactor) sensitive(name string) bool {
+	name = normalizeName(name)
+	if r.names[name] {
+		return true
+	}
+	for n := range r.names {
+		if n != "" && strings.HasSuffix(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) redactString(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllString(s, r.mask())
+	}
+	return s
+}
+
+// masked returns the masked value for a sensitive value of type `t`,
+// the mask when a string fits, the zero value otherwise.
+func (r *Redactor) masked(t reflect.Type) reflect.Value {
+	mask := reflect.ValueOf(r.mask())
+	switch {
+	case t.Kind() == reflect.String:
+		return mask.Convert(t)
+	case mask.Type().AssignableTo(t):
+		return mask
+	}
+	return reflect.Zero(t)
+}
+
+// redactValue returns a redacted copy of `v` and true, or false when
+// nothing in `v` had to be redacted.
+func (r *Redactor) redactValue(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxRedactDepth || !v.IsValid() || unredacted(v) {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if len(r.patterns) == 0 {
+			return v, false
+		}
+		s := r.redactString(v.String())
+		if s == v.String() {
+			return v, false
+		}
+		return reflect.ValueOf(s).Convert(v.Type()), true
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := r.redactValue(v.Elem(), depth+1)
+		if !ok {
+			return v, false
+		}
+		if v.Kind() == reflect.Interface {
+			return elem, true
+		}
+		p := reflect.New(elem.Type())
+		p.Elem().Set(elem)
+		return p, true
+
+	case reflect.Struct:
+		var c reflect.Value
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			var fv reflect.Value
+			var ok bool
+			if (r.sensitive(f.Name) || r.sensitive(jsonName(f))) && !unredacted(v.Field(i)) {
+				fv, ok = r.masked(f.Type), true
+			} else {
+				fv, ok = r.redactValue(v.Field(i), depth+1)
+			}
+			if !ok {
+				continue
+			}
+
+			if !c.IsValid() {
+				c = reflect.New(t).Elem()
+				c.Set(v)
+			}
+			c.Field(i).Set(fv)
+		}
+		if !c.IsValid() {
+			return v, false
+		}
+		return c, true
+
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v, false
+		}
+		var c reflect.Value
+		elemType := v.Type().Elem()
+		iter := v.MapRange()
+		for iter.Next() {
+			var fv reflect.Value
+			var ok bool
+			if r.sensitive(iter.Key().String()) && !unredacted(iter.Value()) {
+				fv, ok = r.masked(elemType), true
+			} else {
+				fv, ok = r.redactValue(iter.Value(), depth+1)
+			}
+			if !ok {
+				continue
+			}
+
+			if !c.IsValid() {
+				c = reflect.MakeMapWithSize(v.Type(), v.Len())
+				copyMap := v.MapRange()
+				for copyMap.Next() {
+					c.SetMapIndex(copyMap.Key(), copyMap.Value())
+				}
+			}
+			c.SetMapIndex(iter.Key(), fv)
+		}
+		if !c.IsValid() {
+			return v, false
+		}
+		return c, true
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			return v, false
+		}
+		var c reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			fv, ok := r.redactValue(v.Index(i), depth+1)
+			if !ok {
+				continue
+			}
+
+			if !c.IsValid() {
+				if v.Kind() == reflect.Slice {
+					c = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(c, v)
+				} else {
+					c = reflect.New(v.Type()).Elem()
+					c.Set(v)
+				}
+			}
+			c.Index(i).Set(fv)
+		}
+		if !c.IsValid() {
+			return v, false
+		}
+		return c, true
+	}
+
+	return v, false
+}
+
+// jsonName returns the json tag name of a struct field.
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// normalizeName lower cases `name` and strips "_" and "-".
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Replace(name, "_", "", -1)
+	return strings.Replace(name, "-", "", -1)
+}
@@ -0,0 +1,114 @@
+package log_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+)
+
+func TestRedactUnredacted(t *testing.T) {
+	type login struct {
+		User     string
+		Password interface{}
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"top level", log.Unredacted{Value: "hunter2"}, "hunter2"},
+		{"map", map[string]interface{}{"password": log.Unredacted{Value: "hunter2"}}, "map[password:hunter2]"},
+		{"map masked", map[string]interface{}{"password": "hunter2"}, "map[password:[REDACTED]]"},
+		{"slice", []interface{}{map[string]interface{}{"password": log.Unredacted{Value: "hunter2"}}}, "[map[password:hunter2]]"},
+		{"struct", login{"bob", log.Unredacted{Value: "hunter2"}}, "{bob hunter2}"},
+		{"struct masked", login{"bob", "hunter2"}, "{bob [REDACTED]}"},
+	}
+
+	r := log.NewRedactor("password")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := log.NewEntry(nil)
+			e.Fields = log.Fields{"req": tt.value}
+			r.Redact(e)
+
+			if got := fmt.Sprint(e.Fields["req"]); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactDefaultFields(t *testing.T) {
+	e := log.NewEntry(nil)
+	e.Fields = log.Fields{
+		"password":      "hunter2",
+		"db_password":   "hunter2",
+		"client_secret": "s3cret",
+		"api_token":     "abc",
+		"APIKey":        "abc",
+		"X-Auth-Token":  "abc",
+		"user":          "bob",
+		"token_count":   3,
+	}
+	log.NewRedactor().Redact(e)
+
+	for _, k := range []string{"password", "db_password", "client_secret", "api_token", "APIKey", "X-Auth-Token"} {
+		if e.Fields[k] != "[REDACTED]" {
+			t.Errorf("%s = %v, want it masked", k, e.Fields[k])
+		}
+	}
+	if e.Fields["user"] != "bob" || e.Fields["token_count"] != 3 {
+		t.Errorf("fields %v, want user and token_count kept", e.Fields)
+	}
+}
+
+func TestRedactNested(t *testing.T) {
+	type credentials struct {
+		User     string
+		Password string `json:"db_password"`
+	}
+
+	config := map[string]interface{}{
+		"database": map[string]interface{}{
+			"host":        "localhost",
+			"db_password": "hunter2",
+		},
+		"login": credentials{"bob", "hunter2"},
+	}
+
+	e := log.NewEntry(nil)
+	e.Fields = log.Fields{"config": config}
+	r := log.NewRedactor()
+	r.Mask = "***"
+	r.Redact(e)
+
+	want := "map[database:map[db_password:*** host:localhost] login:{bob ***}]"
+	if got := fmt.Sprint(e.Fields["config"]); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if config["database"].(map[string]interface{})["db_password"] != "hunter2" {
+		t.Error("the original value was modified")
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	e := log.NewEntry(nil)
+	e.Message = "login of bob@example.com with Bearer abc.def-123"
+	e.Fields = log.Fields{
+		"from":    "alice@example.org",
+		"headers": []string{"Authorization: bearer xyz", "Accept: */*"},
+	}
+	log.NewRedactor().Pattern(log.EmailPattern, log.BearerPattern).Redact(e)
+
+	if e.Message != "login of [REDACTED] with [REDACTED]" {
+		t.Errorf("message = %q", e.Message)
+	}
+	if e.Fields["from"] != "[REDACTED]" {
+		t.Errorf("from = %v", e.Fields["from"])
+	}
+	if got := fmt.Sprint(e.Fields["headers"]); got != "[Authorization: [REDACTED] Accept: */*]" {
+		t.Errorf("headers = %s", got)
+	}
+}
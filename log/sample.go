@@ -0,0 +1,284 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampleKey identifies entries counted together by a Sampler.
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+// sampleCounter counts the entries of a key in the current interval.
+type sampleCounter struct {
+	start   int64
+	n       uint64
+	dropped uint64
+}
+
+// Sampler limits how often the same message is logged at the same level.
+//
+// In every interval the first First entries of a message and level are
+// written, after that every Thereafter-th entry; Thereafter 0 drops all
+// of them. Sample is lock free and safe for concurrent use.
+//
+// Messages that went quiet for two intervals are forgotten, so logging
+// ever changing messages doesn't grow the sampler without bound.
+type Sampler struct {
+	// Interval must be positive, see NewSampler.
+	Interval   time.Duration
+	First      uint64
+	Thereafter uint64
+
+	counters sync.Map
+	pruned   int64
+	stop     chan struct{}
+}
+
+// NewSampler creates a sampler writing the first `first` entries per
+// `interval` and every `thereafter`-th entry after that.
+func NewSampler(interval time.Duration, first, thereafter uint64) (*Sampler, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("sampler interval must be positive, got %s", interval)
+	}
+	return &Sampler{
+		Interval:   interval,
+		First:      first,
+		Thereafter: thereafter,
+	}, nil
+}
+
+// Sample reports whether an entry should be written.
+func (s *Sampler) Sample(level Level, msg string) bool {
+	key := sampleKey{level, msg}
+	now := time.Now().UnixNano()
+	s.prune(now)
+
+	v, ok := s.counters.Load(key)
+	if !ok {
+		v, _ = s.counters.LoadOrStore(key, &sampleCounter{start: now})
+	}
+	c := v.(*sampleCounter)
+
+	start := atomic.LoadInt64(&c.start)
+	if now-start >= int64(s.Interval) && atomic.CompareAndSwapInt64(&c.start, start, now) {
+		atomic.StoreUint64(&c.n, 0)
+	}
+
+	n := atomic.AddUint64(&c.n, 1)
+	if n <= s.First || (s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0) {
+		return true
+	}
+
+	atomic.AddUint64(&c.dropped, 1)
+	return false
+}
+
+// prune forgets the messages that went quiet, at most once an interval.
+// Their dropped entries are still counted by the metrics of the logger,
+// only the summary Flush would write for them is lost.
+func (s *Sampler) prune(now int64) {
+	last := atomic.LoadInt64(&s.pruned)
+	if s.Interval <= 0 || now-last < int64(s.Interval) || !atomic.CompareAndSwapInt64(&s.pruned, last, now) {
+		return
+	}
+
+	s.counters.Range(func(k, v interface{}) bool {
+		if now-atomic.LoadInt64(&v.(*sampleCounter).start) >= 2*int64(s.Interval) {
+			s.counters.Delete(k)
+		}
+		return true
+	})
+}
+
+// Flush writes a summary entry to `l` for every message that had entries
+// dropped since the last flush, and forgets messages that went quiet.
+func (s *Sampler) Flush(l *Logger) {
+	now := time.Now().UnixNano()
+
+	s.counters.Range(func(k, v interface{}) bool {
+		key := k.(sampleKey)
+		c := v.(*sampleCounter)
+
+		dropped := atomic.SwapUint64(&c.dropped, 0)
+		if dropped == 0 {
+			if now-atomic.LoadInt64(&c.start) >= 2*int64(s.Interval) {
+				s.counters.Delete(k)
+			}
+			return true
+		}
+
+		msg := fmt.Sprintf("suppressed %d similar messages", dropped)
+		e := NewEntry(l).WithFields(Fields{
+			"suppressed": dropped,
+			"sampled":    key.msg,
+		})
+		l.write(key.level, e, msg, 1)
+		return true
+	})
+}
+
+// Start flushes summaries to `l` every interval until Stop is called.
+// Nothing is started without a positive Interval, Logger.Flush still
+// writes the summaries.
+func (s *Sampler) Start(l *Logger) {
+	if s.Interval <= 0 {
+		return
+	}
+	s.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.Flush(l)
+			case <-stop:
+				return
+			}
+		}
+	}(s.stop)
+}
+
+// Stop stops the flushing started by Start.
+func (s *Sampler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// rateLimited is a reporter dropping entries above a rate.
+type rateLimited struct {
+	sync.Mutex
+	reporter Reporter
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	dropped  uint64
+	logger   *Logger
+	timer    *time.Timer
+}
+
+// RateLimited wraps `r` in a token bucket allowing `perSecond` entries a
+// second with bursts of up to `burst` entries. A summary of the dropped
+// entries is written once entries are allowed again, or by Flush and
+// Close when that is sooner.
+func RateLimited(r Reporter, perSecond float64, burst int) Reporter {
+	return &rateLimited{
+		reporter: r,
+		rate:     perSecond,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+func (r *rateLimited) Write(e *Entry, calldepth int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	r.refill(now)
+
+	if r.tokens < 1 {
+		r.dropped++
+		r.logger = e.Logger
+		if e.Logger != nil {
+			e.Logger.Metrics().drop(DroppedRateLimit)
+		}
+		r.schedule()
+		return nil
+	}
+	r.tokens--
+
+	if err := r.summarize(now, calldepth+1); err != nil {
+		return err
+	}
+
+	return r.reporter.Write(e, calldepth+1)
+}
+
+// refill adds the tokens earned since the last call.
+func (r *rateLimited) refill(now time.Time) {
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+}
+
+// schedule writes the summary once the next token is available, so it
+// isn't held back until the next entry. r must be locked.
+func (r *rateLimited) schedule() {
+	if r.timer != nil || r.rate <= 0 {
+		return
+	}
+
+	wait := time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+	r.timer = time.AfterFunc(wait, func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.timer = nil
+		if err := r.summarize(time.Now(), 1); err != nil {
+			stderr.Printf("error logging: %s", err)
+		}
+	})
+}
+
+// summarize writes a summary of the dropped entries, if any. r must be
+// locked.
+func (r *rateLimited) summarize(now time.Time, calldepth int) error {
+	if r.dropped == 0 {
+		return nil
+	}
+
+	summary := &Entry{
+		Logger:    r.logger,
+		Level:     WARN,
+		Message:   fmt.Sprintf("rate limit suppressed %d messages", r.dropped),
+		Fields:    Fields{"suppressed": r.dropped},
+		Timestamp: now,
+	}
+	r.dropped = 0
+
+	return r.reporter.Write(summary, calldepth+1)
+}
+
+// Flush writes the pending summary and flushes the wrapped reporter when
+// it buffers entries.
+func (r *rateLimited) Flush() error {
+	r.Lock()
+	err := r.summarize(time.Now(), 1)
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return flushReporter(r.reporter)
+}
+
+// Close writes the pending summary and closes the wrapped reporter when
+// it is an io.Closer.
+func (r *rateLimited) Close() error {
+	r.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	err := r.summarize(time.Now(), 1)
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return closeReporter(r.reporter)
+}
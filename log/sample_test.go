@@ -0,0 +1,125 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recorder keeps the messages written to it.
+type recorder struct {
+	sync.Mutex
+	messages []string
+}
+
+func (r *recorder) Write(e *Entry, calldepth int) error {
+	r.Lock()
+	defer r.Unlock()
+	r.messages = append(r.messages, e.Message)
+	return nil
+}
+
+func (r *recorder) get() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func TestSamplerPrunes(t *testing.T) {
+	s, err := NewSampler(10*time.Millisecond, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		s.Sample(INFO, string(rune('a'+i)))
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	s.Sample(INFO, "new")
+
+	n := 0
+	s.counters.Range(func(k, v interface{}) bool {
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Errorf("%d counters kept, want only the new message", n)
+	}
+}
+
+func TestNewSamplerInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewSampler(interval, 1, 0); err == nil {
+			t.Errorf("interval %s accepted", interval)
+		}
+	}
+
+	// a literal without Interval neither starts nor prunes
+	s := &Sampler{First: 1}
+	s.Start(NewLogger(DEBUG, nil))
+	s.Stop()
+	s.Sample(INFO, "one")
+	s.Sample(INFO, "two")
+	if atomic.LoadInt64(&s.pruned) != 0 {
+		t.Error("sampler without Interval pruned")
+	}
+}
+
+func TestSamplerFlushedByLogger(t *testing.T) {
+	rec := &recorder{}
+	l := NewLogger(DEBUG, []Reporter{rec})
+	s, err := NewSampler(time.Hour, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Sampler = s
+
+	for i := 0; i < 3; i++ {
+		l.Named("db").Info("entry")
+	}
+	if err := l.Named("db").Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rec.get()
+	if len(got) != 2 || got[1] != "suppressed 2 similar messages" {
+		t.Errorf("wrote %q, want the entry and a summary", got)
+	}
+}
+
+func TestRateLimitedSummaryOnTimer(t *testing.T) {
+	rec := &recorder{}
+	l := NewLogger(DEBUG, []Reporter{RateLimited(rec, 50, 1)})
+
+	for i := 0; i < 3; i++ {
+		l.Info("entry")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.get()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got := rec.get()
+	if len(got) != 2 || got[1] != "rate limit suppressed 2 messages" {
+		t.Errorf("wrote %q, want the entry and a summary", got)
+	}
+}
+
+func TestRateLimitedSummaryOnFlush(t *testing.T) {
+	rec := &recorder{}
+	l := NewLogger(DEBUG, []Reporter{RateLimited(rec, 0.001, 1)})
+
+	for i := 0; i < 3; i++ {
+		l.Info("entry")
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rec.get()
+	if len(got) != 2 || got[1] != "rate limit suppressed 2 messages" {
+		t.Errorf("wrote %q, want the entry and a summary", got)
+	}
+}
@@ -0,0 +1,154 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// slogHandler is a slog.Handler writing records to a Logger.
+type slogHandler struct {
+	logger *Logger
+	fields Fields
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler writing records to `l`. Attributes
+// become fields, attributes in groups are named "group.name", and the
+// values of registered context keys are added as WithContext does.
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(log.Log)))
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{logger: l, fields: Fields{}}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Enabled(fromSlogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.group, a)
+		return true
+	})
+
+	e := NewEntry(h.logger).WithContext(ctx).WithFields(fields)
+	h.logger.Write(fromSlogLevel(r.Level), e, r.Message, 1)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	for _, a := range attrs {
+		addAttr(fields, h.group, a)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, group: h.group}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, fields: h.fields, group: h.group + name + "."}
+}
+
+// addAttr sets `a` as a field, prefixing its name with `group`.
+func addAttr(fields Fields, group string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, group, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[group+a.Key] = v.Any()
+}
+
+// fromSlogLevel maps slog levels to the nearest level, levels above
+// slog.LevelError are ERROR so a record never exits the process.
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARN
+	case level >= slog.LevelInfo:
+		return INFO
+	default:
+		return DEBUG
+	}
+}
+
+// toSlogLevel maps levels to slog levels, FATAL is above slog.LevelError.
+func toSlogLevel(level Level) slog.Level {
+	switch level {
+	case FATAL:
+		return slog.LevelError + 4
+	case ERROR:
+		return slog.LevelError
+	case WARN:
+		return slog.LevelWarn
+	case INFO:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// slogReporter is a Reporter forwarding entries to a slog.Handler.
+type slogReporter struct {
+	handler slog.Handler
+}
+
+// NewSlogReporter returns a reporter forwarding entries to `h`, with the
+// fields as attributes sorted by name and the caller as the source.
+func NewSlogReporter(h slog.Handler) Reporter {
+	return slogReporter{handler: h}
+}
+
+func (s slogReporter) Write(e *Entry, calldepth int) error {
+	ctx := context.Background()
+	level := toSlogLevel(e.Level)
+	if !s.handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	r := slog.NewRecord(e.Timestamp, level, e.Message, sourcePC(e))
+	for _, name := range e.Fields.Names() {
+		r.AddAttrs(slog.Any(name, e.Fields[name]))
+	}
+	return s.handler.Handle(ctx, r)
+}
+
+// sourcePC returns the program counter of the caller of `e` for the
+// source of a slog record. It is 0 when the caller was inlined into
+// another function, its program counter would resolve to the wrong one.
+func sourcePC(e *Entry) uintptr {
+	frame, ok := e.Caller()
+	if !ok {
+		return 0
+	}
+
+	pc := frame.PC + 1
+	resolved, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if resolved.Function != frame.Function || resolved.Line != frame.Line {
+		return 0
+	}
+	return pc
+}
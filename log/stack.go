@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// NoStack disables capturing stacks, see SetStackLevel.
+const NoStack Level = -1
+
+// maxCauses limits how many wrapped errors WithError walks.
+const maxCauses = 16
+
+// Frame is a function call of a Stack.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Stack is a call stack, innermost call first. Captured stacks are set
+// as the "callstack" field.
+type Stack []Frame
+
+// String formats the stack like a panic does, a line with the function
+// followed by a tab indented line with the file of every call.
+func (s Stack) String() string {
+	return s.Indent("")
+}
+
+// Indent formats the stack like String with every line starting with
+// `prefix`.
+func (s Stack) Indent(prefix string) string {
+	var b bytes.Buffer
+	for i, f := range s {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s%s\n%s\t%s:%d", prefix, f.Function, prefix, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// callStack returns the stack above Logger.write, see callers.
+func callStack() Stack {
+	var s Stack
+	callers(func(f runtime.Frame) bool {
+		s = append(s, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		return true
+	})
+	return s
+}
+
+// errorStack returns the stack of a pkg/errors error without the
+// runtime frames.
+func errorStack(err stackTracer) Stack {
+	var s Stack
+	for _, f := range err.StackTrace() {
+		pc := uintptr(f) - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil || strings.HasPrefix(fn.Name(), "runtime.") {
+			continue
+		}
+		file, line := fn.FileLine(pc)
+		s = append(s, Frame{Function: fn.Name(), File: file, Line: line})
+	}
+	return s
+}
+
+// causes returns the errors wrapped by `err`, outermost first. Errors
+// are unwrapped with Unwrap, Cause and, for multierror, WrappedErrors.
+func causes(err error) []error {
+	var list []error
+	queue := []error{err}
+
+	for len(queue) > 0 && len(list) < maxCauses {
+		err, queue = queue[0], queue[1:]
+
+		var next []error
+		switch e := err.(type) {
+		case interface{ WrappedErrors() []error }:
+			next = e.WrappedErrors()
+		case interface{ Unwrap() []error }:
+			next = e.Unwrap()
+		case interface{ Unwrap() error }:
+			next = []error{e.Unwrap()}
+		case interface{ Cause() error }:
+			next = []error{e.Cause()}
+		}
+
+		for _, n := range next {
+			if n != nil && n != err {
+				list = append(list, n)
+				queue = append(queue, n)
+			}
+		}
+	}
+	return list
+}
+
+// causeChain returns the messages of the errors wrapped by `err` and
+// the stack of the innermost one carrying a pkg/errors stack.
+func causeChain(err error) ([]string, Stack) {
+	var messages []string
+	var stack Stack
+
+	if s, ok := err.(stackTracer); ok {
+		stack = errorStack(s)
+	}
+
+	// wrappers without a message of their own repeat their cause
+	last := err.Error()
+	for _, cause := range causes(err) {
+		if msg := strings.TrimSpace(cause.Error()); msg != last {
+			messages = append(messages, msg)
+			last = msg
+		}
+		if s, ok := cause.(stackTracer); ok {
+			stack = errorStack(s)
+		}
+	}
+	return messages, stack
+}
+
+// GetStackLevel returns the level up to which entries get a stack.
+func (l *Logger) GetStackLevel() Level {
+	// stored plus one, so the zero value of a Logger is NoStack
+	return Level(atomic.LoadInt32(&l.core().stackLevel) - 1)
+}
+
+// SetStackLevel captures the call stack of entries at `level` and more
+// severe as their "callstack" field, NoStack disables it. Stacks are off
+// by default. It is safe for concurrent use.
+func (l *Logger) SetStackLevel(level Level) {
+	atomic.StoreInt32(&l.core().stackLevel, int32(level)+1)
+}
+
+// SetStackLevel sets the stack level of the default logger.
+func SetStackLevel(level Level) {
+	Default().SetStackLevel(level)
+}
@@ -0,0 +1,116 @@
+package log_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/logtest"
+	"github.com/pkg/errors"
+)
+
+func TestStacksOffByDefault(t *testing.T) {
+	rec := logtest.New()
+	loggers := map[string]*log.Logger{
+		"NewLogger": log.NewLogger(log.DEBUG, []log.Reporter{rec}),
+		"literal":   {Mutex: new(sync.Mutex), Reporters: []log.Reporter{rec}},
+	}
+
+	for name, l := range loggers {
+		if got := l.GetStackLevel(); got != log.NoStack {
+			t.Errorf("%s: GetStackLevel = %v, want NoStack", name, got)
+		}
+		l.Write(log.FATAL, log.NewEntry(l), "fatal", 0)
+	}
+
+	for _, e := range rec.Entries() {
+		if _, ok := e.Fields["callstack"]; ok {
+			t.Errorf("entry has a callstack: %v", e.Fields)
+		}
+	}
+}
+
+func TestStackLevel(t *testing.T) {
+	l, rec := logtest.NewLogger()
+	l.SetStackLevel(log.ERROR)
+
+	l.Error("error")
+	l.Warn("warn")
+
+	entries := rec.Entries()
+	stack, ok := entries[0].Fields["callstack"].(log.Stack)
+	if !ok || len(stack) == 0 {
+		t.Fatalf("ERROR has no callstack: %v", entries[0].Fields)
+	}
+	if fn := stack[0].Function; !strings.HasSuffix(fn, ".TestStackLevel") {
+		t.Errorf("the stack starts at %s, want TestStackLevel", fn)
+	}
+	if _, ok := entries[1].Fields["callstack"]; ok {
+		t.Error("WARN has a callstack")
+	}
+
+	l.SetStackLevel(log.NoStack)
+	l.Error("error")
+	if _, ok := rec.Last().Fields["callstack"]; ok {
+		t.Error("NoStack still captures stacks")
+	}
+}
+
+// wrapper wraps an error without a message of its own.
+type wrapper struct{ err error }
+
+func (w wrapper) Error() string { return w.err.Error() }
+func (w wrapper) Unwrap() error { return w.err }
+
+func TestWithErrorCauses(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	inner := errors.New("inner")
+	err := fmt.Errorf("outer: %w", wrapper{errors.Wrap(inner, "middle")})
+	l.WithError(err).Error("failed")
+
+	fields := rec.Last().Fields
+	if fields["error"] != "outer: middle: inner" {
+		t.Errorf("error = %v", fields["error"])
+	}
+	if causes, _ := fields["causes"].([]string); strings.Join(causes, " | ") != "middle: inner | inner" {
+		t.Errorf("causes = %q, want middle: inner and inner", causes)
+	}
+
+	stack, ok := fields["callstack"].(log.Stack)
+	if !ok || !strings.HasSuffix(stack[0].Function, ".TestWithErrorCauses") {
+		t.Fatalf("callstack = %v, want the stack of inner", fields["callstack"])
+	}
+	if source, _ := fields["source"].(string); !strings.HasPrefix(source, "TestWithErrorCauses: ") {
+		t.Errorf("source = %q", source)
+	}
+}
+
+func TestWithErrorWithoutCauses(t *testing.T) {
+	l, rec := logtest.NewLogger()
+
+	l.WithError(fmt.Errorf("plain")).Error("failed")
+
+	for _, name := range []string{"causes", "callstack", "source"} {
+		if _, ok := rec.Last().Fields[name]; ok {
+			t.Errorf("a plain error sets %s", name)
+		}
+	}
+}
+
+func TestStackString(t *testing.T) {
+	s := log.Stack{
+		{Function: "main.run", File: "/src/main.go", Line: 12},
+		{Function: "main.main", File: "/src/main.go", Line: 5},
+	}
+
+	want := "main.run\n\t/src/main.go:12\nmain.main\n\t/src/main.go:5"
+	if got := s.String(); got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	if got := s.Indent("> "); !strings.HasPrefix(got, "> main.run\n> \t/src/main.go:12\n") {
+		t.Errorf("Indent = %q", got)
+	}
+}
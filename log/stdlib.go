@@ -0,0 +1,54 @@
+package log
+
+import (
+	stdlog "log"
+	"os"
+	"strings"
+)
+
+// stderr prints the errors of the loggers themselves and backs the
+// default reporter. It doesn't use the standard logger, RedirectStdLog
+// may send that back into a Logger.
+var stderr = stdlog.New(os.Stderr, "", stdlog.LstdFlags)
+
+// Writer is an io.Writer turning every write into an entry, for
+// libraries that log through the standard library. Entries report the
+// caller of the standard logger as their file and function.
+type Writer struct {
+	Logger *Logger
+	Level  Level
+}
+
+// NewWriter returns a writer logging every write to `l` at `level`.
+func NewWriter(l *Logger, level Level) *Writer {
+	return &Writer{Logger: l, Level: level}
+}
+
+// Write logs `p` as one entry without the trailing newline.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+	w.Logger.Write(w.Level, NewEntry(w.Logger), msg, 1)
+	return len(p), nil
+}
+
+// NewStdLogger returns a standard library logger writing to `l` at
+// `level`, for libraries that take one such as http.Server.ErrorLog.
+func NewStdLogger(l *Logger, level Level) *stdlog.Logger {
+	return stdlog.New(NewWriter(l, level), "", 0)
+}
+
+// RedirectStdLog sends the output of the standard logger to `l` at
+// `level`. The standard logger's timestamp is left out, entries carry
+// their own. Call the returned function to restore the previous output.
+func RedirectStdLog(l *Logger, level Level) (restore func()) {
+	flags := stdlog.Flags()
+	out := stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(NewWriter(l, level))
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetOutput(out)
+	}
+}
@@ -0,0 +1,162 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/keiwi/utils/log"
+	"github.com/keiwi/utils/log/handlers/ring"
+	"github.com/nats-io/go-nats"
+)
+
+// SubscribeLogLevel lets the levels of `l` be changed at runtime through
+// `subject`. A message holding a spec such as "info,nats=debug" sets the
+// levels, an empty message only queries them. Requests are answered with
+// the current spec or the error.
+func SubscribeLogLevel(state *nats.Conn, subject string, l *log.Logger) (*nats.Subscription, error) {
+	return state.Subscribe(subject, func(msg *nats.Msg) {
+		resp := ""
+		if len(msg.Data) > 0 {
+			if err := l.SetLevels(string(msg.Data)); err != nil {
+				resp = "error: " + err.Error()
+			}
+		}
+		if resp == "" {
+			resp = l.LevelSpec()
+		}
+
+		if msg.Reply != "" {
+			state.Publish(msg.Reply, []byte(resp))
+		}
+	})
+}
+
+// logAlert is the message published by AlertHook and LogReporter.
+type logAlert struct {
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// AlertHook returns a log hook publishing every ERROR and FATAL entry
+// as JSON on `subject`.
+func AlertHook(state *nats.Conn, subject string) log.Hook {
+	alert := log.HookFunc(func(e *log.Entry) error {
+		return publishEntry(state, subject, e)
+	})
+
+	return log.FilteredHook(alert, log.MinLevel(log.ERROR))
+}
+
+// LogReporter returns a log reporter publishing every entry as JSON on
+// `subject`.
+func LogReporter(state *nats.Conn, subject string) log.Reporter {
+	return logReporter{state: state, subject: subject}
+}
+
+// RegisterLogReporter makes the "nats" reporter type available to
+// log.FromConfig, publishing on `state` to the "subject" option.
+func RegisterLogReporter(state *nats.Conn) {
+	log.RegisterReporter("nats", func(o log.Options) (log.Reporter, error) {
+		var config struct{ Subject string }
+		if err := o.Decode(&config); err != nil {
+			return nil, fmt.Errorf("nats logger: %v", err)
+		}
+		if config.Subject == "" {
+			return nil, fmt.Errorf("nats logger: missing subject")
+		}
+		return LogReporter(state, config.Subject), nil
+	})
+}
+
+type logReporter struct {
+	state   *nats.Conn
+	subject string
+}
+
+func (r logReporter) Write(e *log.Entry, calldepth int) error {
+	return publishEntry(r.state, r.subject, e)
+}
+
+// publishEntry publishes `e` as JSON on `subject`.
+func publishEntry(state *nats.Conn, subject string, e *log.Entry) error {
+	fields := map[string]string{}
+	for k, v := range e.Fields {
+		fields[k] = fmt.Sprint(v)
+	}
+
+	data, err := json.Marshal(logAlert{
+		Level:     log.Levels[e.Level].Name,
+		Message:   e.Message,
+		Fields:    fields,
+		Timestamp: e.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	return state.Publish(subject, data)
+}
+
+// RecoverHandler wraps a subscription handler so a panic while handling a
+// message is logged with the subject, see log.Recover, instead of
+// crashing the process.
+func RecoverHandler(handler nats.MsgHandler, opts ...log.RecoverOption) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		defer log.WithField("subject", msg.Subject).Recover(opts...)
+		handler(msg)
+	}
+}
+
+// ServeLogRing answers queries for the entries kept by `r` on `subject`.
+// A request holds a ring.Query as JSON, an empty one selects all
+// entries, and is answered with the matching records as a JSON array or
+// "error: ..." when the query is invalid. At most 100 records are sent
+// unless the query sets a limit.
+func ServeLogRing(state *nats.Conn, subject string, r *ring.Ring) (*nats.Subscription, error) {
+	return state.Subscribe(subject, func(msg *nats.Msg) {
+		if msg.Reply == "" {
+			return
+		}
+
+		var q ring.Query
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &q); err != nil {
+				state.Publish(msg.Reply, []byte("error: "+err.Error()))
+				return
+			}
+		}
+		if q.Limit == 0 {
+			q.Limit = 100
+		}
+
+		records := r.Query(q)
+		if records == nil {
+			records = []ring.Record{}
+		}
+		data, err := json.Marshal(records)
+		if err != nil {
+			data = []byte("error: " + err.Error())
+		}
+		state.Publish(msg.Reply, data)
+	})
+}
+
+// TailLogRing publishes the entries written to `r` matching `q` as JSON
+// on `subject`, one message per record, until stop is called.
+func TailLogRing(state *nats.Conn, subject string, r *ring.Ring, q ring.Query) (stop func()) {
+	_, ch, cancel := r.Subscribe(q)
+
+	go func() {
+		for rec := range ch {
+			data, err := json.Marshal(rec)
+			if err != nil {
+				continue
+			}
+			state.Publish(subject, data)
+		}
+	}()
+
+	return cancel
+}